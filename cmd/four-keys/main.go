@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+	// embed timezone database for --timezone on systems without it
+	_ "time/tzdata"
 
 	"github.com/hmiyado/four-keys/internal/cli"
 )
@@ -14,6 +14,8 @@ func DefaultApp(version string) *cli.App {
 		Commands: []*cli.Command{
 			GetCommandReleases(),
 			GetCommandTimeSeries(),
+			GetCommandAggregate(),
+			GetCommandCompare(),
 		},
 		OnUsageError: onUsageError,
 	}
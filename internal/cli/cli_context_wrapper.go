@@ -2,6 +2,7 @@ package cli
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/urfave/cli/v2"
@@ -31,8 +32,13 @@ func (c *CliContextWrapper) Debugln(a ...any) {
 	}
 }
 
+// timerMutex protects timerMap because timers run concurrently
+var timerMutex sync.Mutex
+
 func (c *CliContextWrapper) StartTimer(key string) {
 	if c.isDebug() {
+		timerMutex.Lock()
+		defer timerMutex.Unlock()
 		if timerMap == nil {
 			timerMap = make(map[string]time.Time)
 		}
@@ -43,6 +49,8 @@ func (c *CliContextWrapper) StartTimer(key string) {
 
 func (c *CliContextWrapper) StopTimer(key string) {
 	if c.isDebug() {
+		timerMutex.Lock()
+		defer timerMutex.Unlock()
 		c.Debugln("Stop_Timer:", key, "\t", time.Since(timerMap[key]))
 		delete(timerMap, key)
 	}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+// repositoryPath is a repository of util.ExampleGitGraph on disk.
+// It is passed to --repository so that tests do not depend on network.
+var repositoryPath string
+
+func TestMain(m *testing.M) {
+	directory, err := os.MkdirTemp("", "four-keys-cli-test")
+	if err != nil {
+		panic(err)
+	}
+	_, err = util.NewRepositoryBuilderOnDisk(directory).GitGraph(util.ExampleGitGraph).Build()
+	if err != nil {
+		panic(err)
+	}
+	repositoryPath = directory
+	// release cache is written in a temporary directory instead of the user cache directory
+	cacheDirectory, err := os.MkdirTemp("", "four-keys-cli-test-cache")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CACHE_HOME", cacheDirectory)
+
+	code := m.Run()
+
+	os.RemoveAll(directory)
+	os.RemoveAll(cacheDirectory)
+	os.Exit(code)
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/hmiyado/four-keys/internal/core"
+	"github.com/urfave/cli/v2"
+)
+
+type AggregateCliOutput struct {
+	Option *core.Option `json:"option"`
+	// MetricsCliOutput is four keys of releases of all repositories
+	MetricsCliOutput
+	Repositories []RepositoryMetricsCliOutput `json:"repositories"`
+}
+
+type RepositoryMetricsCliOutput struct {
+	Name string `json:"name"`
+	// Error is the reason why the repository is not aggregated
+	Error string `json:"error,omitempty"`
+	// MetricsCliOutput is nil if Error occurred
+	*MetricsCliOutput
+}
+
+// repositoryReleases are releases of a repository of the repository list
+type repositoryReleases struct {
+	entry    RepositoryListEntry
+	option   *core.Option
+	releases []*core.Release
+	err      error
+}
+
+func GetCommandAggregate() *cli.Command {
+	return &cli.Command{
+		Name:  "aggregate",
+		Usage: "aggregate four keys of repositories in the repository list",
+		Flags: append(getCommandReleasesFlags(), getCommandAggregateFlags()...),
+		Action: func(ctx *cli.Context) error {
+			context := &CliContextWrapper{context: ctx}
+			context.Debugln("In debug mode")
+			// global files and paths are rejected before they are read
+			aggregateOption, err := context.AggregateOption()
+			if err != nil {
+				return err
+			}
+			option, err := context.Option()
+			if err != nil {
+				return err
+			}
+			thresholds, err := context.PerformanceThresholds()
+			if err != nil {
+				return err
+			}
+
+			results := queryReleasesOfRepositories(context, aggregateOption, option)
+
+			context.StartTimer("Calculate metrics")
+			output := &AggregateCliOutput{
+				Option:       option,
+				Repositories: make([]RepositoryMetricsCliOutput, 0),
+			}
+			allReleases := make([]*core.Release, 0)
+			for _, result := range results {
+				if result.err != nil {
+					output.Repositories = append(output.Repositories, RepositoryMetricsCliOutput{
+						Name:  result.entry.Name,
+						Error: result.err.Error(),
+					})
+					continue
+				}
+				allReleases = append(allReleases, result.releases...)
+				metrics := mapReleasesToMetricsCliOutput(result.releases, result.option, thresholds)
+				output.Repositories = append(output.Repositories, RepositoryMetricsCliOutput{
+					Name:             result.entry.Name,
+					MetricsCliOutput: &metrics,
+				})
+			}
+			output.MetricsCliOutput = mapReleasesToMetricsCliOutput(allReleases, option, thresholds)
+			outputJson, err := json.Marshal(output)
+			context.StopTimer("Calculate metrics")
+			if err != nil {
+				context.Error(err)
+				return err
+			}
+			context.Write(outputJson)
+			return nil
+		},
+		OnUsageError: onUsageError,
+	}
+}
+
+// queryReleasesOfRepositories queries releases of each repository by aggregateOption.Workers workers.
+// The results are in the same order as aggregateOption.Repositories.
+func queryReleasesOfRepositories(context *CliContextWrapper, aggregateOption *AggregateOption, option *core.Option) []repositoryReleases {
+	results := make([]repositoryReleases, len(aggregateOption.Repositories))
+	indices := make(chan int)
+	var waitGroup sync.WaitGroup
+	accessToken := context.context.String("accessToken")
+	for worker := 0; worker < aggregateOption.Workers; worker++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for i := range indices {
+				entry := aggregateOption.Repositories[i]
+				result := repositoryReleases{entry: entry, option: entry.Option(option)}
+				context.StartTimer("Query " + entry.Name)
+				repository, err := entry.Repository(accessToken)
+				if err != nil {
+					context.Debugln("Cannot open", entry.Name, ":", err)
+					result.err = err
+				} else {
+					result.option.ReleaseCache = context.ReleaseCache(repositoryIdentity(repository, entry.Url))
+					result.releases = core.QueryReleases(repository, result.option)
+					context.SaveReleaseCache(result.option.ReleaseCache)
+				}
+				context.StopTimer("Query " + entry.Name)
+				results[i] = result
+			}
+		}()
+	}
+	for i := range aggregateOption.Repositories {
+		indices <- i
+	}
+	close(indices)
+	waitGroup.Wait()
+	return results
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/hmiyado/four-keys/internal/core"
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// RepositoryListEntry is a repository of the repository list.
+// Either of Url or Path is required.
+// Relative Path, Deployments and Incidents are resolved against the directory of the repository list.
+type RepositoryListEntry struct {
+	// Name defaults to the base name of Url or Path
+	Name string `yaml:"name"`
+	// Url is cloned in memory
+	Url string `yaml:"url"`
+	// Path is opened as local repository
+	Path string `yaml:"path"`
+	// IgnorePattern overrides --ignorePattern
+	IgnorePattern string `yaml:"ignorePattern"`
+	// FixCommitPattern overrides --fixCommitPattern
+	FixCommitPattern string `yaml:"fixCommitPattern"`
+	// Paths are used instead of --path, which is not available with aggregate
+	Paths []string `yaml:"paths"`
+	// Deployments is the deployment event log file of the repository, which is used instead of --deployments
+	Deployments string `yaml:"deployments"`
+	// Environment overrides --environment
+	Environment string `yaml:"environment"`
+	// Incidents is the incident file of the repository, which is used instead of --incidents
+	Incidents string `yaml:"incidents"`
+	// FailureSource overrides --failureSource. It defaults to incidents if Incidents is specified
+	FailureSource string `yaml:"failureSource"`
+
+	deployments []core.Deployment
+	incidents   []core.Incident
+}
+
+type repositoryList struct {
+	Repositories []RepositoryListEntry `yaml:"repositories"`
+}
+
+type AggregateOption struct {
+	Repositories []RepositoryListEntry
+	// Workers is the max number of repositories queried concurrently
+	Workers int
+}
+
+const defaultWorkers = 4
+
+func getCommandAggregateFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:     "repositories",
+			Usage:    "repository list file (YAML). each repository has url or path, and optional name, ignorePattern, fixCommitPattern, paths, deployments, environment, incidents and failureSource",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  "workers",
+			Usage: "max number of repositories queried concurrently",
+			Value: defaultWorkers,
+		},
+	}
+}
+
+// readRepositoryList reads the repository list from path.
+func readRepositoryList(path string) ([]RepositoryListEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var list repositoryList
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	if len(list.Repositories) == 0 {
+		return nil, errors.New("repositories should not be empty")
+	}
+	directory := filepath.Dir(path)
+	resolvePath := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(directory, path)
+	}
+	for i := range list.Repositories {
+		entry := &list.Repositories[i]
+		if (entry.Url == "") == (entry.Path == "") {
+			return nil, fmt.Errorf("repositories[%v]: either of url or path is required", i)
+		}
+		if entry.Name == "" {
+			entry.Name = strings.TrimSuffix(filepath.Base(entry.Url+entry.Path), ".git")
+		}
+		entry.Path = resolvePath(entry.Path)
+		for _, pattern := range []string{entry.IgnorePattern, entry.FixCommitPattern} {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return nil, fmt.Errorf("repositories[%v]: %v", i, err)
+			}
+		}
+		if entry.Deployments != "" {
+			entry.Deployments = resolvePath(entry.Deployments)
+			if entry.deployments, err = core.ReadDeployments(entry.Deployments); err != nil {
+				return nil, fmt.Errorf("repositories[%v]: deployments: %v", i, err)
+			}
+		}
+		if entry.Incidents != "" {
+			entry.Incidents = resolvePath(entry.Incidents)
+			if entry.incidents, err = core.ReadIncidents(entry.Incidents); err != nil {
+				return nil, fmt.Errorf("repositories[%v]: incidents: %v", i, err)
+			}
+		}
+		switch core.FailureSource(entry.FailureSource) {
+		case "", core.FailureSourceFixCommits:
+		case core.FailureSourceIncidents, core.FailureSourceCombined:
+			if entry.Incidents == "" {
+				return nil, fmt.Errorf("repositories[%v]: incidents is required when failureSource is %s", i, entry.FailureSource)
+			}
+		default:
+			return nil, fmt.Errorf("repositories[%v]: unavailable failureSource \"%s\"", i, entry.FailureSource)
+		}
+	}
+	return list.Repositories, nil
+}
+
+func (c *CliContextWrapper) AggregateOption() (*AggregateOption, error) {
+	// files and paths of a repository are specified for each repository, so that they are not applied to the others
+	for name, field := range map[string]string{"path": "paths", "deployments": "deployments", "incidents": "incidents"} {
+		if c.context.IsSet(name) {
+			wrappedError := fmt.Errorf("[invalid %v] %v cannot be used with aggregate. specify %v of each repository in repositories", name, name, field)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+	}
+	repositories, err := readRepositoryList(c.context.String("repositories"))
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid repositories] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+	for i, entry := range repositories {
+		// the same commit deployed to each environment would be counted as multiple releases
+		environments := core.DeploymentEnvironments(entry.deployments)
+		if entry.Environment == "" && c.context.String("environment") == "" && len(environments) > 1 {
+			wrappedError := fmt.Errorf("[invalid repositories] repositories[%v]: environment is required because deployments have environments %q", i, environments)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+	}
+	workers := c.context.Int("workers")
+	if workers < 1 {
+		wrappedError := fmt.Errorf("[invalid workers] workers should be positive but %v", workers)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+	return &AggregateOption{
+		Repositories: repositories,
+		Workers:      workers,
+	}, nil
+}
+
+// Repository opens or clones the repository of the entry.
+func (entry RepositoryListEntry) Repository(accessToken string) (*git.Repository, error) {
+	if entry.Path != "" {
+		repository, err := git.PlainOpen(entry.Path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open repository: %v", entry.Path)
+		}
+		return repository, nil
+	}
+	return cloneRepository(entry.Url, accessToken)
+}
+
+// Option returns option for the repository of the entry based on option.
+func (entry RepositoryListEntry) Option(option *core.Option) *core.Option {
+	repositoryOption := *option
+	repositoryOption.IsLocalRepository = entry.Path != ""
+	repositoryOption.Paths = entry.Paths
+	if entry.deployments != nil {
+		repositoryOption.ReleaseSource = core.ReleaseSourceDeployment
+		repositoryOption.Deployments = entry.deployments
+	}
+	if entry.Environment != "" {
+		repositoryOption.DeploymentEnvironment = entry.Environment
+	}
+	if entry.incidents != nil {
+		repositoryOption.Incidents = entry.incidents
+		repositoryOption.FailureSource = core.FailureSourceIncidents
+	}
+	if entry.FailureSource != "" {
+		repositoryOption.FailureSource = core.FailureSource(entry.FailureSource)
+	}
+	if entry.IgnorePattern != "" {
+		repositoryOption.IgnorePattern = regexp.MustCompile(entry.IgnorePattern)
+	}
+	if entry.FixCommitPattern != "" {
+		fixCommitPattern := regexp.MustCompile(entry.FixCommitPattern)
+		repositoryOption.FixCommitPattern = fixCommitPattern
+		repositoryOption.FailureDetectors = make([]core.FailureDetector, 0, len(option.FailureDetectors))
+		for _, detector := range option.FailureDetectors {
+			if _, ok := detector.(core.FixCommitDetector); ok {
+				detector = core.FixCommitDetector{Pattern: fixCommitPattern}
+			}
+			repositoryOption.FailureDetectors = append(repositoryOption.FailureDetectors, detector)
+		}
+	}
+	return &repositoryOption
+}
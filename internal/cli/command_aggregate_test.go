@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hmiyado/four-keys/internal/util"
+	"github.com/urfave/cli/v2"
+)
+
+func TestGetCommandAggregateShouldReturnMetricsOfEachRepositoryAndAll(t *testing.T) {
+	directory := t.TempDir()
+	anotherRepositoryPath := filepath.Join(directory, "another")
+	_, err := util.NewRepositoryBuilderOnDisk(anotherRepositoryPath).GitGraph(`gitGraph
+  commit id: "a1" date: "2023-04-01" tag: "v1"
+  commit id: "a2" msg: "fix: a2" date: "2023-04-10" tag: "v2"
+`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	repositoriesPath := filepath.Join(directory, "repos.yaml")
+	os.WriteFile(repositoriesPath, []byte(fmt.Sprintf(`repositories:
+  - name: example
+    path: %v
+  - url: %v
+    fixCommitPattern: "^fix:"
+  - name: broken
+    path: %v
+`, repositoryPath, anotherRepositoryPath, filepath.Join(directory, "not_found"))), 0644)
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"aggregate",
+		"--repositories", repositoriesPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--workers", "2",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err = GetCommandAggregate().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput AggregateCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Repositories) != 3 {
+		t.Fatalf("repositories should be example, another and broken but %v", cliOutput.Repositories)
+	}
+	example, another, broken := cliOutput.Repositories[0], cliOutput.Repositories[1], cliOutput.Repositories[2]
+	if example.Name != "example" || another.Name != "another" || broken.Name != "broken" {
+		t.Errorf("repositories should keep the order of the list but %v, %v, %v", example.Name, another.Name, broken.Name)
+	}
+	if broken.Error == "" {
+		t.Errorf("broken repository should have error")
+	}
+	// v2.0.0 of example is the failure of 3 releases, and v1 of another is the failure of 2 releases by its fixCommitPattern
+	util.AssertIsNearBy(t, example.ChangeFailureRate, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, another.ChangeFailureRate, 0.5, 0.01)
+	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0.4, 0.01)
+	util.AssertIsNearBy(t, cliOutput.DeploymentFrequency, example.DeploymentFrequency+another.DeploymentFrequency, 0.01)
+}
+
+func TestGetCommandAggregateShouldBeFailWithInvalidRepositories(t *testing.T) {
+	repositoriesPath := filepath.Join(t.TempDir(), "repos.yaml")
+	os.WriteFile(repositoriesPath, []byte(`repositories:
+  - name: nowhere
+`), 0644)
+	output := bytes.NewBuffer([]byte{})
+	errOutput := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: errOutput}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"aggregate", "--repositories", repositoriesPath}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	error := GetCommandAggregate().Run(cCtx, args...)
+
+	if error == nil {
+		t.Errorf("repository without url and path does not return error. log: %v", output.String())
+	}
+	if !strings.Contains(error.Error(), "[invalid repositories]") {
+		t.Errorf("repository without url and path does not return error of --repositories. error: %v", error.Error())
+	}
+}
+
+func TestGetCommandAggregateShouldUseFilesOfEachRepositoryRelativeToRepositories(t *testing.T) {
+	directory := t.TempDir()
+	_, err := util.NewRepositoryBuilderOnDisk(filepath.Join(directory, "another")).GitGraph(`gitGraph
+  commit id: "a1" date: "2023-04-01" tag: "v1"
+  commit id: "a2" msg: "fix: a2" date: "2023-04-10" tag: "v2"
+`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(directory, "incidents.yaml"), []byte(`- release: v1
+  start: 2023-04-02T00:00:00Z
+  resolved: 2023-04-03T00:00:00Z
+- release: v2
+  start: 2023-04-11T00:00:00Z
+  resolved: 2023-04-12T00:00:00Z
+`), 0644)
+	repositoriesPath := filepath.Join(directory, "repos.yaml")
+	os.WriteFile(repositoriesPath, []byte(fmt.Sprintf(`repositories:
+  - name: example
+    path: %v
+  - path: another
+    incidents: incidents.yaml
+`, repositoryPath)), 0644)
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"aggregate",
+		"--repositories", repositoriesPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err = GetCommandAggregate().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput AggregateCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Repositories) != 2 {
+		t.Fatalf("repositories should be example and another but %v", cliOutput.Repositories)
+	}
+	example, another := cliOutput.Repositories[0], cliOutput.Repositories[1]
+	if another.Error != "" {
+		t.Fatalf("relative path should be resolved against the directory of repositories but %v", another.Error)
+	}
+	// incidents of another are not applied to example
+	util.AssertIsNearBy(t, example.ChangeFailureRate, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, another.ChangeFailureRate, 1, 0.01)
+}
+
+func TestGetCommandAggregateShouldBeFailWithFilesOfAllRepositories(t *testing.T) {
+	repositoriesPath := filepath.Join(t.TempDir(), "repos.yaml")
+	os.WriteFile(repositoriesPath, []byte(fmt.Sprintf(`repositories:
+  - path: %v
+`, repositoryPath)), 0644)
+	testCases := []struct {
+		flag string
+		args []string
+	}{
+		{flag: "path", args: []string{"--path", "README.md"}},
+		{flag: "deployments", args: []string{"--deployments", "deployments.json"}},
+		{flag: "incidents", args: []string{"--incidents", "incidents.json"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.flag, func(t *testing.T) {
+			output := bytes.NewBuffer([]byte{})
+			errOutput := bytes.NewBuffer([]byte{})
+			app := &cli.App{Writer: output, ErrWriter: errOutput}
+			set := flag.NewFlagSet("test", 0)
+			args := append([]string{"aggregate", "--repositories", repositoriesPath}, testCase.args...)
+			_ = set.Parse(args)
+
+			cCtx := cli.NewContext(app, set, nil)
+			error := GetCommandAggregate().Run(cCtx, args...)
+
+			if error == nil {
+				t.Fatalf("--%v with aggregate does not return error. log: %v", testCase.flag, output.String())
+			}
+			if !strings.Contains(error.Error(), fmt.Sprintf("[invalid %v]", testCase.flag)) {
+				t.Errorf("--%v with aggregate does not return error of --%v. error: %v", testCase.flag, testCase.flag, error.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/core"
+	"github.com/urfave/cli/v2"
+)
+
+type CompareCliOutput struct {
+	Current CompareRangeCliOutput   `json:"current"`
+	Base    CompareRangeCliOutput   `json:"base"`
+	Changes CompareChangesCliOutput `json:"changes"`
+}
+
+type CompareRangeCliOutput struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	MetricsCliOutput
+}
+
+// CompareChangesCliOutput are changes of each metric from the base range to the current range.
+// Durations are in days.
+type CompareChangesCliOutput struct {
+	DeploymentFrequency MetricChangeCliOutput `json:"deploymentFrequency"`
+	LeadTimeForChanges  MetricChangeCliOutput `json:"leadTimeForChanges"`
+	TimeToRestore       MetricChangeCliOutput `json:"timeToRestore"`
+	ChangeFailureRate   MetricChangeCliOutput `json:"changeFailureRate"`
+	ReworkRate          MetricChangeCliOutput `json:"reworkRate"`
+}
+
+type MetricChangeCliOutput struct {
+	Current float64 `json:"current"`
+	Base    float64 `json:"base"`
+	// Delta is Current - Base
+	Delta float64 `json:"delta"`
+	// RelativeDelta is Delta / Base. It is nil if Base is zero
+	RelativeDelta *float64 `json:"relativeDelta"`
+	// IsImprovement is true if the metric got better. Higher is better for deployment frequency, and lower is better for others
+	IsImprovement bool `json:"isImprovement"`
+}
+
+func GetCommandCompare() *cli.Command {
+	return &cli.Command{
+		Name:  "compare",
+		Usage: "compare four keys of since and until with the base range",
+		Flags: append(getCommandReleasesFlags(), getCommandCompareFlags()...),
+		Action: func(ctx *cli.Context) error {
+			context := &CliContextWrapper{context: ctx}
+			context.Debugln("In debug mode")
+			option, err := context.Option()
+			if err != nil {
+				return err
+			}
+			compareOption, err := context.CompareOption(option)
+			if err != nil {
+				return err
+			}
+			thresholds, err := context.PerformanceThresholds()
+			if err != nil {
+				return err
+			}
+			repository, err := context.Repository()
+			if err != nil {
+				context.Error(err)
+				return err
+			}
+
+			option.ReleaseCache = context.ReleaseCache(repositoryIdentity(repository, ctx.String("repository")))
+			defer context.SaveReleaseCache(option.ReleaseCache)
+			baseOption := *option
+			baseOption.Since = compareOption.BaseSince
+			baseOption.Until = compareOption.BaseUntil
+			currentReleases := core.QueryReleases(repository, option)
+			baseReleases := core.QueryReleases(repository, &baseOption)
+
+			context.StartTimer("Calculate metrics")
+			current := CompareRangeCliOutput{
+				Since:            option.Since,
+				Until:            option.Until,
+				MetricsCliOutput: mapReleasesToMetricsCliOutput(currentReleases, option, thresholds),
+			}
+			base := CompareRangeCliOutput{
+				Since:            baseOption.Since,
+				Until:            baseOption.Until,
+				MetricsCliOutput: mapReleasesToMetricsCliOutput(baseReleases, &baseOption, thresholds),
+			}
+			output := &CompareCliOutput{
+				Current: current,
+				Base:    base,
+				Changes: CompareChangesCliOutput{
+					DeploymentFrequency: newMetricChange(current.DeploymentFrequency, base.DeploymentFrequency, true),
+					LeadTimeForChanges:  newMetricChange(current.LeadTimeForChanges.Present(), base.LeadTimeForChanges.Present(), false),
+					TimeToRestore:       newMetricChange(current.TimeToRestore.Present(), base.TimeToRestore.Present(), false),
+					ChangeFailureRate:   newMetricChange(current.ChangeFailureRate, base.ChangeFailureRate, false),
+					ReworkRate:          newMetricChange(current.ReworkRate, base.ReworkRate, false),
+				},
+			}
+			outputJson, err := json.Marshal(output)
+			context.StopTimer("Calculate metrics")
+			if err != nil {
+				context.Error(err)
+				return err
+			}
+			context.Write(outputJson)
+			return nil
+		},
+		OnUsageError: onUsageError,
+	}
+}
+
+func newMetricChange(current float64, base float64, higherIsBetter bool) MetricChangeCliOutput {
+	change := MetricChangeCliOutput{
+		Current: current,
+		Base:    base,
+		Delta:   current - base,
+	}
+	if base != 0 {
+		relativeDelta := change.Delta / base
+		change.RelativeDelta = &relativeDelta
+	}
+	if higherIsBetter {
+		change.IsImprovement = change.Delta > 0
+	} else {
+		change.IsImprovement = change.Delta < 0
+	}
+	return change
+}
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/core"
+	"github.com/urfave/cli/v2"
+)
+
+// CompareOption is the base range compared with the range of --since and --until.
+type CompareOption struct {
+	// inclusive
+	BaseSince time.Time
+	// inclusive
+	BaseUntil time.Time
+}
+
+func getCommandCompareFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{
+			Name:  "previous",
+			Usage: "compare with the equally long period just before since",
+		},
+		&cli.TimestampFlag{
+			Name:   "baseSince",
+			Usage:  "the start date of the base range to compare with (inclusive)",
+			Layout: "2006-01-02",
+		},
+		&cli.TimestampFlag{
+			Name:   "baseUntil",
+			Usage:  "the end date of the base range to compare with (inclusive)",
+			Layout: "2006-01-02",
+		},
+	}
+}
+
+// CompareOption returns the base range of --previous, or --baseSince and --baseUntil.
+func (c *CliContextWrapper) CompareOption(option *core.Option) (*CompareOption, error) {
+	baseSince := c.context.Timestamp("baseSince")
+	baseUntil := c.context.Timestamp("baseUntil")
+	var err error
+	compareOption := &CompareOption{}
+	switch {
+	case c.context.Bool("previous") && (baseSince != nil || baseUntil != nil):
+		err = errors.New("previous cannot be used with baseSince and baseUntil")
+	case c.context.Bool("previous"):
+		compareOption.BaseUntil = option.Since.Add(-time.Second)
+		compareOption.BaseSince = compareOption.BaseUntil.Add(-option.Until.Sub(option.Since))
+	case baseSince == nil || baseUntil == nil:
+		err = errors.New("either of previous or both of baseSince and baseUntil is required")
+	default:
+		compareOption.BaseSince = inLocation(*baseSince, option.Location)
+		compareOption.BaseUntil = inLocation(*baseUntil, option.Location).AddDate(0, 0, 1).Add(-time.Second)
+		if compareOption.BaseUntil.Before(compareOption.BaseSince) {
+			err = errors.New("baseUntil should not be before baseSince")
+		}
+	}
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid compare] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+	return compareOption, nil
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/util"
+	"github.com/urfave/cli/v2"
+)
+
+func TestGetCommandCompareShouldCompareWithPreviousPeriod(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"compare",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--previous",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandCompare().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput CompareCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	expectedBaseSince := time.Date(2023, 2, 28, 0, 0, 0, 0, time.UTC)
+	expectedBaseUntil := time.Date(2023, 3, 30, 23, 59, 59, 0, time.UTC)
+	if !cliOutput.Base.Since.Equal(expectedBaseSince) || !cliOutput.Base.Until.Equal(expectedBaseUntil) {
+		t.Errorf("base range should be from %v to %v but from %v to %v", expectedBaseSince, expectedBaseUntil, cliOutput.Base.Since, cliOutput.Base.Until)
+	}
+	deploymentFrequency := cliOutput.Changes.DeploymentFrequency
+	util.AssertIsNearBy(t, deploymentFrequency.Current, 0.1, 0.01)
+	util.AssertIsNearBy(t, deploymentFrequency.Base, 0.03333333333333333, 0.01)
+	if deploymentFrequency.RelativeDelta == nil || !deploymentFrequency.IsImprovement {
+		t.Errorf("more deployments should be improvement but %+v", deploymentFrequency)
+	} else {
+		util.AssertIsNearBy(t, *deploymentFrequency.RelativeDelta, 2, 0.01)
+	}
+	leadTimeForChanges := cliOutput.Changes.LeadTimeForChanges
+	util.AssertIsNearBy(t, -leadTimeForChanges.Delta, 4, 0.01)
+	if !leadTimeForChanges.IsImprovement {
+		t.Errorf("shorter lead time should be improvement but %+v", leadTimeForChanges)
+	}
+	changeFailureRate := cliOutput.Changes.ChangeFailureRate
+	util.AssertIsNearBy(t, changeFailureRate.Current, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, changeFailureRate.Base, 0, 0.01)
+	util.AssertIsNearBy(t, changeFailureRate.Delta, 0.3333333333333333, 0.01)
+	if changeFailureRate.RelativeDelta != nil || changeFailureRate.IsImprovement {
+		t.Errorf("change failure rate from zero should be regression without relative delta but %+v", changeFailureRate)
+	}
+}
+
+func TestGetCommandCompareShouldCompareWithBaseRange(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"compare",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--baseSince", "2023-03-31",
+		"--baseUntil", "2023-04-30",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandCompare().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput CompareCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	for name, change := range map[string]MetricChangeCliOutput{
+		"deploymentFrequency": cliOutput.Changes.DeploymentFrequency,
+		"leadTimeForChanges":  cliOutput.Changes.LeadTimeForChanges,
+		"timeToRestore":       cliOutput.Changes.TimeToRestore,
+		"changeFailureRate":   cliOutput.Changes.ChangeFailureRate,
+	} {
+		if change.Delta != 0 || change.IsImprovement {
+			t.Errorf("%v of the same range should not change but %+v", name, change)
+		}
+	}
+}
+
+func TestGetCommandCompareShouldBeFailWithInvalidRange(t *testing.T) {
+	testCases := [][]string{
+		{},
+		{"--baseSince", "2023-03-01"},
+		{"--previous", "--baseSince", "2023-03-01", "--baseUntil", "2023-03-31"},
+		{"--baseSince", "2023-03-31", "--baseUntil", "2023-03-01"},
+	}
+	for _, testCase := range testCases {
+		app := &cli.App{Writer: bytes.NewBuffer([]byte{}), ErrWriter: bytes.NewBuffer([]byte{})}
+		set := flag.NewFlagSet("test", 0)
+		args := append([]string{"compare", "--repository", repositoryPath}, testCase...)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandCompare().Run(cCtx, args...)
+
+		if err == nil || !strings.Contains(err.Error(), "[invalid compare]") {
+			t.Errorf("compare %v should be fail but %v", testCase, err)
+		}
+	}
+}
@@ -2,23 +2,71 @@ package cli
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/hmiyado/four-keys/internal/core"
 	"github.com/urfave/cli/v2"
 )
 
 type DefaultCliOutput struct {
-	Option              *core.Option         `json:"option"`
-	DeploymentFrequency float64              `json:"deploymentFrequency"`
-	LeadTimeForChanges  DurationWithTimeUnit `json:"leadTimeForChanges"`
-	TimeToRestore       DurationWithTimeUnit `json:"timeToRestore"`
-	ChangeFailureRate   float64              `json:"changeFailureRate"`
+	Option *core.Option `json:"option"`
+	MetricsCliOutput
+	// Components are four keys of each component in monorepo mode
+	Components []ComponentMetricsCliOutput `json:"components,omitempty"`
+}
+
+type ComponentMetricsCliOutput struct {
+	Component string `json:"component"`
+	MetricsCliOutput
+}
+
+type MetricsCliOutput struct {
+	DeploymentFrequency           float64                                `json:"deploymentFrequency"`
+	LeadTimeForChanges            DurationWithTimeUnit                   `json:"leadTimeForChanges"`
+	LeadTimeForChangesPercentiles LeadTimeForChangesPercentilesCliOutput `json:"leadTimeForChangesPercentiles"`
+	TimeToRestore                 DurationWithTimeUnit                   `json:"timeToRestore"`
+	TimeToRestoreStatistics       TimeToRestoreStatisticsCliOutput       `json:"timeToRestoreStatistics"`
+	ChangeFailureRate             float64                                `json:"changeFailureRate"`
+	ReworkRate                    float64                                `json:"reworkRate"`
+	// BatchSize is mean and median of batch size of releases. It is present only if --batchSize
+	BatchSize   *core.BatchSizeStatistics `json:"batchSize,omitempty"`
+	Performance core.Performance          `json:"performance"`
+	// WorkingTime is lead time for changes and time to restore in working time. It is present only if the working calendar is specified
+	WorkingTime *WorkingTimeCliOutput `json:"workingTime,omitempty"`
+}
+
+type WorkingTimeCliOutput struct {
+	LeadTimeForChanges            DurationWithTimeUnit                   `json:"leadTimeForChanges"`
+	LeadTimeForChangesPercentiles LeadTimeForChangesPercentilesCliOutput `json:"leadTimeForChangesPercentiles"`
+	TimeToRestore                 DurationWithTimeUnit                   `json:"timeToRestore"`
+	TimeToRestoreStatistics       TimeToRestoreStatisticsCliOutput       `json:"timeToRestoreStatistics"`
+}
+
+type LeadTimeForChangesPercentilesCliOutput struct {
+	Median DurationWithTimeUnit `json:"median"`
+	P75    DurationWithTimeUnit `json:"p75"`
+	P90    DurationWithTimeUnit `json:"p90"`
+	P95    DurationWithTimeUnit `json:"p95"`
+}
+
+type TimeToRestoreStatisticsCliOutput struct {
+	Count        int                    `json:"count"`
+	Mean         DurationWithTimeUnit   `json:"mean"`
+	Median       DurationWithTimeUnit   `json:"median"`
+	Max          DurationWithTimeUnit   `json:"max"`
+	Restorations []RestorationCliOutput `json:"restorations"`
+}
+
+type RestorationCliOutput struct {
+	FailedRelease    string               `json:"failedRelease"`
+	RestoringRelease string               `json:"restoringRelease"`
+	TimeToRestore    DurationWithTimeUnit `json:"timeToRestore"`
 }
 
 func defaultAction(ctx *cli.Context) error {
 	context := &CliContextWrapper{context: ctx}
 	context.Debugln("In debug mode")
-	releases, err := QueryReleases(context)
+	releases, components, err := QueryReleases(context)
 	if err != nil {
 		context.Error(err)
 		return err
@@ -28,15 +76,23 @@ func defaultAction(ctx *cli.Context) error {
 		context.Error(err)
 		return err
 	}
+	thresholds, err := context.PerformanceThresholds()
+	if err != nil {
+		return err
+	}
 
 	context.StartTimer("Calculate metrics")
-	outputJson, err := json.Marshal(&DefaultCliOutput{
-		Option:              option,
-		DeploymentFrequency: core.GetDeploymentFrequency(releases, *option),
-		LeadTimeForChanges:  getDurationWithTimeUnit(core.GetMeanLeadTimeForChanges(releases)),
-		TimeToRestore:       getDurationWithTimeUnit(core.GetTimeToRestore(releases)),
-		ChangeFailureRate:   core.GetChangeFailureRate(releases),
-	})
+	output := &DefaultCliOutput{
+		Option:           option,
+		MetricsCliOutput: mapReleasesToMetricsCliOutput(releases, option, thresholds),
+	}
+	for _, component := range components {
+		output.Components = append(output.Components, ComponentMetricsCliOutput{
+			Component:        component.Component,
+			MetricsCliOutput: mapReleasesToMetricsCliOutput(component.Releases, option, thresholds),
+		})
+	}
+	outputJson, err := json.Marshal(output)
 	context.StopTimer("Calculate metrics")
 	if err != nil {
 		context.Error(err)
@@ -44,5 +100,92 @@ func defaultAction(ctx *cli.Context) error {
 	}
 	context.Write(outputJson)
 	return nil
+}
 
+func mapReleasesToMetricsCliOutput(releases []*core.Release, option *core.Option, thresholds core.PerformanceThresholds) MetricsCliOutput {
+	deploymentFrequency := core.GetDeploymentFrequency(releases, *option)
+	leadTimeForChangesStatistics := core.GetLeadTimeForChangesStatistics(releases, option.LeadTimeMode)
+	timeToRestoreStatistics := core.GetTimeToRestoreStatistics(releases)
+	changeFailureRate := core.GetChangeFailureRate(releases)
+	var batchSize *core.BatchSizeStatistics
+	if option.CountsChangedLines {
+		batchSizeStatistics := core.GetBatchSizeStatistics(releases)
+		batchSize = &batchSizeStatistics
+	}
+	var workingTime *WorkingTimeCliOutput
+	if option.WorkingCalendar != nil {
+		workingLeadTimeForChangesStatistics := core.GetWorkingLeadTimeForChangesStatistics(releases, option.LeadTimeMode, option.WorkingCalendar)
+		workingTimeToRestoreStatistics := core.GetWorkingTimeToRestoreStatistics(releases, option.WorkingCalendar)
+		workingTime = &WorkingTimeCliOutput{
+			LeadTimeForChanges:            getDurationWithTimeUnit(workingLeadTimeForChangesStatistics.Mean),
+			LeadTimeForChangesPercentiles: mapLeadTimeForChangesPercentilesToCliOutput(workingLeadTimeForChangesStatistics),
+			TimeToRestore:                 getDurationWithTimeUnit(workingTimeToRestoreStatistics.Mean),
+			TimeToRestoreStatistics:       mapTimeToRestoreStatisticsToCliOutput(workingTimeToRestoreStatistics),
+		}
+	}
+	return MetricsCliOutput{
+		DeploymentFrequency:           deploymentFrequency,
+		LeadTimeForChanges:            getDurationWithTimeUnit(leadTimeForChangesStatistics.Mean),
+		LeadTimeForChangesPercentiles: mapLeadTimeForChangesPercentilesToCliOutput(leadTimeForChangesStatistics),
+		TimeToRestore:                 getDurationWithTimeUnit(timeToRestoreStatistics.Mean),
+		TimeToRestoreStatistics:       mapTimeToRestoreStatisticsToCliOutput(timeToRestoreStatistics),
+		ChangeFailureRate:             changeFailureRate,
+		ReworkRate:                    core.GetReworkRate(releases),
+		BatchSize:                     batchSize,
+		Performance:                   classifyPerformance(releases, deploymentFrequency, option.LeadTimeMode, leadTimeForChangesStatistics, timeToRestoreStatistics, changeFailureRate, thresholds),
+		WorkingTime:                   workingTime,
+	}
+}
+
+// classifyPerformance classifies four keys of releases by thresholds.
+// Lead time without lead times, time to restore without restorations and change failure rate without releases are unknown.
+func classifyPerformance(releases []*core.Release, deploymentFrequency float64, leadTimeMode core.LeadTimeMode, leadTimeForChangesStatistics core.LeadTimeForChangesStatistics, timeToRestoreStatistics core.TimeToRestoreStatistics, changeFailureRate float64, thresholds core.PerformanceThresholds) core.Performance {
+	var leadTimeForChanges, timeToRestore *time.Duration
+	if len(core.GetLeadTimesForChanges(releases, leadTimeMode)) > 0 {
+		leadTimeForChanges = &leadTimeForChangesStatistics.Mean
+	}
+	if timeToRestoreStatistics.Count > 0 {
+		timeToRestore = &timeToRestoreStatistics.Mean
+	}
+	var changeFailureRateOfReleases *float64
+	if len(releases) > 0 {
+		changeFailureRateOfReleases = &changeFailureRate
+	}
+	return core.ClassifyPerformance(deploymentFrequency, leadTimeForChanges, timeToRestore, changeFailureRateOfReleases, thresholds)
+}
+
+func mapLeadTimeForChangesPercentilesToCliOutput(statistics core.LeadTimeForChangesStatistics) LeadTimeForChangesPercentilesCliOutput {
+	return LeadTimeForChangesPercentilesCliOutput{
+		Median: getDurationWithTimeUnit(statistics.Median),
+		P75:    getDurationWithTimeUnit(statistics.P75),
+		P90:    getDurationWithTimeUnit(statistics.P90),
+		P95:    getDurationWithTimeUnit(statistics.P95),
+	}
+}
+
+func mapTimeToRestoreStatisticsToCliOutput(statistics core.TimeToRestoreStatistics) TimeToRestoreStatisticsCliOutput {
+	return TimeToRestoreStatisticsCliOutput{
+		Count:        statistics.Count,
+		Mean:         getDurationWithTimeUnit(statistics.Mean),
+		Median:       getDurationWithTimeUnit(statistics.Median),
+		Max:          getDurationWithTimeUnit(statistics.Max),
+		Restorations: mapRestorationsToCliOutput(statistics.Restorations),
+	}
+}
+
+func mapRestorationsToCliOutput(restorations []core.Restoration) []RestorationCliOutput {
+	output := make([]RestorationCliOutput, 0)
+	for _, restoration := range restorations {
+		restorationOutput := RestorationCliOutput{
+			TimeToRestore: getDurationWithTimeUnit(restoration.TimeToRestore),
+		}
+		if restoration.FailedRelease != nil {
+			restorationOutput.FailedRelease = restoration.FailedRelease.Tag
+		}
+		if restoration.RestoringRelease != nil {
+			restorationOutput.RestoringRelease = restoration.RestoringRelease.Tag
+		}
+		output = append(output, restorationOutput)
+	}
+	return output
 }
@@ -3,8 +3,12 @@ package cli
 import (
 	"bytes"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/hmiyado/four-keys/internal/core"
 	"github.com/hmiyado/four-keys/internal/util"
 	"github.com/urfave/cli/v2"
 )
@@ -18,7 +22,7 @@ func TestDefaultAppShouldReturnMetricsWithRepositoryUrlSinceUntil(t *testing.T)
 		Writer: output,
 	}
 
-	err := testApp.Run([]string{"four-keys", "--repository", "https://github.com/go-git/go-git", "--since", "2020-01-01", "--until", "2020-12-31"})
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-01", "--until", "2023-03-31"})
 	if err != nil {
 		t.Error(err)
 	}
@@ -26,13 +30,13 @@ func TestDefaultAppShouldReturnMetricsWithRepositoryUrlSinceUntil(t *testing.T)
 	json.Unmarshal(output.Bytes(), &cliOutput)
 	// intended output
 	// {
-	//   "option":{"since":"2020-01-01T00:00:00Z","until":"2020-12-31T23:59:59Z"},
-	//   "deploymentFrequency":0.00821917808219178,
-	//   "leadTimeForChanges":{"value":140.8096334876543,"unit":"day"},
+	//   "option":{"since":"2023-03-01T00:00:00Z","until":"2023-03-31T23:59:59Z"},
+	//   "deploymentFrequency":0.03333333333333333,
+	//   "leadTimeForChanges":{"value":9,"unit":"day"},
 	//   "changeFailureRate":0
 	// }
-	util.AssertIsNearBy(t, cliOutput.DeploymentFrequency, 0.00821917808219178, 0.01)
-	util.AssertIsNearBy(t, cliOutput.LeadTimeForChanges.Present(), 140.8096334876543, 0.01)
+	util.AssertIsNearBy(t, cliOutput.DeploymentFrequency, 0.03333333333333333, 0.01)
+	util.AssertIsNearBy(t, cliOutput.LeadTimeForChanges.Present(), 9, 0.01)
 	util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 0, 0.01)
 	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0, 0.01)
 }
@@ -46,14 +50,40 @@ func TestDefaultAppShouldReturnTimeToRestoreAndChangeFailureRate(t *testing.T) {
 		Writer: output,
 	}
 
-	err := testApp.Run([]string{"four-keys", "--repository", "https://github.com/go-git/go-git", "--since", "2015-12-01", "--until", "2016-05-31"})
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"})
 	if err != nil {
 		t.Error(err)
 	}
 	var cliOutput DefaultCliOutput
 	json.Unmarshal(output.Bytes(), &cliOutput)
-	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0.08333333333333333, 0.01)
-	util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 2.7969791666666666, 0.01)
+	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 15, 0.01)
+	util.AssertIsNearBy(t, cliOutput.ReworkRate, 0.3333333333333333, 0.01)
+	if cliOutput.BatchSize != nil {
+		t.Errorf("batch size should not be present without batchSize but %+v", cliOutput.BatchSize)
+	}
+}
+
+func TestDefaultAppShouldReturnBatchSize(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--batchSize"})
+	if err != nil {
+		t.Error(err)
+	}
+	var cliOutput DefaultCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if cliOutput.BatchSize == nil {
+		t.Fatalf("batch size should be present with batchSize")
+	}
+	util.AssertIsNearBy(t, cliOutput.BatchSize.Mean.Commits, 2.3333333333333335, 0.01)
+	util.AssertIsNearBy(t, cliOutput.BatchSize.Median.Commits, 3, 0.01)
 }
 
 func TestDefaultAppShouldRunWithoutOption(t *testing.T) {
@@ -71,3 +101,280 @@ func TestDefaultAppShouldRunWithoutOption(t *testing.T) {
 	}
 
 }
+
+func TestDefaultAppShouldReturnTimeToRestoreOfIncidents(t *testing.T) {
+	incidentsPath := filepath.Join(t.TempDir(), "incidents.yaml")
+	os.WriteFile(incidentsPath, []byte(`
+- start: 2023-04-16T00:00:00Z
+  resolved: 2023-04-18T00:00:00Z
+  release: v2.0.0
+  severity: major
+`), 0644)
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--incidents", incidentsPath})
+	if err != nil {
+		t.Error(err)
+	}
+	var cliOutput DefaultCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 2, 0.01)
+	if cliOutput.TimeToRestoreStatistics.Count != 1 || cliOutput.TimeToRestoreStatistics.Restorations[0].FailedRelease != "v2.0.0" {
+		t.Errorf("v2.0.0 should be restored by incident resolution but %+v", cliOutput.TimeToRestoreStatistics)
+	}
+}
+
+func TestDefaultAppShouldReturnWorkingTimeByWorkingCalendar(t *testing.T) {
+	directory := t.TempDir()
+	incidentsPath := filepath.Join(directory, "incidents.yaml")
+	// 2023-04-16 is Sunday, so only Monday 2023-04-17 is working day of the incident
+	os.WriteFile(incidentsPath, []byte(`
+- start: 2023-04-16T00:00:00Z
+  resolved: 2023-04-18T00:00:00Z
+  release: v2.0.0
+  severity: major
+`), 0644)
+	holidaysPath := filepath.Join(directory, "holidays.yaml")
+	os.WriteFile(holidaysPath, []byte("- 2023-04-17\n"), 0644)
+	testCases := []struct {
+		name                  string
+		args                  []string
+		expectedTimeToRestore float64
+	}{
+		{"working hours", []string{"--workingHours", "09:00-18:00"}, 0.375},
+		{"working days", []string{"--workingDays", "sunday", "--workingDays", "monday"}, 2},
+		{"holidays", []string{"--holidays", holidaysPath}, 0},
+	}
+	for _, testCase := range testCases {
+		output := bytes.NewBuffer([]byte{})
+		defaltApp := DefaultApp("")
+		testApp := &cli.App{
+			Flags:  defaltApp.Flags,
+			Action: defaltApp.Action,
+			Writer: output,
+		}
+
+		args := append([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--incidents", incidentsPath}, testCase.args...)
+		err := testApp.Run(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cliOutput DefaultCliOutput
+		json.Unmarshal(output.Bytes(), &cliOutput)
+		if cliOutput.WorkingTime == nil {
+			t.Fatalf("%v: working time should be output", testCase.name)
+		}
+		util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 2, 0.01)
+		if cliOutput.WorkingTime.TimeToRestore.Present() != testCase.expectedTimeToRestore {
+			t.Errorf("%v: working time to restore should be %v but %v", testCase.name, testCase.expectedTimeToRestore, cliOutput.WorkingTime.TimeToRestore.Present())
+		}
+		if *cliOutput.WorkingTime.LeadTimeForChanges.Duration > *cliOutput.LeadTimeForChanges.Duration {
+			t.Errorf("%v: working lead time for changes should not be longer than %v but %v", testCase.name, cliOutput.LeadTimeForChanges.Present(), cliOutput.WorkingTime.LeadTimeForChanges.Present())
+		}
+	}
+}
+
+func TestDefaultAppShouldNotReturnWorkingTimeByDefault(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(output.String(), "workingTime") {
+		t.Errorf("working time should not be output without working calendar but %v", output.String())
+	}
+}
+
+func TestDefaultAppShouldBeFailWithInvalidWorkingCalendar(t *testing.T) {
+	for _, args := range [][]string{
+		{"--workingDays", "caturday"},
+		{"--workingHours", "18:00-09:00"},
+		{"--workingHours", "9am-6pm"},
+		{"--holidays", filepath.Join(t.TempDir(), "not_found.yaml")},
+	} {
+		defaltApp := DefaultApp("")
+		testApp := &cli.App{
+			Flags:     defaltApp.Flags,
+			Action:    defaltApp.Action,
+			Writer:    bytes.NewBuffer([]byte{}),
+			ErrWriter: bytes.NewBuffer([]byte{}),
+		}
+
+		err := testApp.Run(append([]string{"four-keys", "--repository", repositoryPath}, args...))
+		if err == nil || !strings.Contains(err.Error(), "[invalid workingCalendar]") {
+			t.Errorf("%v should return error of working calendar but %v", args, err)
+		}
+	}
+}
+
+func TestDefaultAppShouldExcludeLeadTimeOfInitialRelease(t *testing.T) {
+	// lead times of v2.0.1, v2.0.0, v1.0.0 and the initial release v0.1.0 are 5, 10, 0 and 9 days
+	testCases := []struct {
+		args     []string
+		expected float64
+	}{
+		{[]string{}, 6},
+		{[]string{"--excludeInitialRelease"}, 5},
+	}
+	for _, testCase := range testCases {
+		output := bytes.NewBuffer([]byte{})
+		defaltApp := DefaultApp("")
+		testApp := &cli.App{
+			Flags:  defaltApp.Flags,
+			Action: defaltApp.Action,
+			Writer: output,
+		}
+
+		err := testApp.Run(append([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-01", "--until", "2023-04-30"}, testCase.args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var cliOutput DefaultCliOutput
+		json.Unmarshal(output.Bytes(), &cliOutput)
+		util.AssertIsNearBy(t, cliOutput.LeadTimeForChanges.Present(), testCase.expected, 0.01)
+		// deployment frequency is not affected
+		util.AssertIsNearBy(t, cliOutput.DeploymentFrequency, 4.0/60, 0.001)
+	}
+}
+
+func TestDefaultAppShouldBeFailWithoutIncidentsForIncidentsFailureSource(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:     defaltApp.Flags,
+		Action:    defaltApp.Action,
+		Writer:    output,
+		ErrWriter: bytes.NewBuffer([]byte{}),
+	}
+
+	err := testApp.Run([]string{"four-keys", "--failureSource", "incidents"})
+	if err == nil || !strings.Contains(err.Error(), "[invalid incidents]") {
+		t.Errorf("--failureSource incidents without --incidents should return error of --incidents but %v", err)
+	}
+}
+
+func TestDefaultAppShouldReturnPerformanceByDefaultThresholds(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"})
+	if err != nil {
+		t.Error(err)
+	}
+	var cliOutput DefaultCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	expected := core.Performance{
+		DeploymentFrequency: core.PerformanceTierHigh,
+		LeadTimeForChanges:  core.PerformanceTierHigh,
+		TimeToRestore:       core.PerformanceTierLow,
+		ChangeFailureRate:   core.PerformanceTierMedium,
+		Overall:             core.PerformanceTierLow,
+	}
+	if cliOutput.Performance != expected {
+		t.Errorf("performance should be %+v but %+v", expected, cliOutput.Performance)
+	}
+}
+
+func TestDefaultAppShouldReturnPerformanceByThresholdsFile(t *testing.T) {
+	thresholdsPath := filepath.Join(t.TempDir(), "thresholds.yaml")
+	os.WriteFile(thresholdsPath, []byte(`timeToRestore:
+  elite: 720h
+changeFailureRate:
+  elite: 0.5
+`), 0644)
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--performanceThresholds", thresholdsPath})
+	if err != nil {
+		t.Error(err)
+	}
+	var cliOutput DefaultCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if cliOutput.Performance.TimeToRestore != core.PerformanceTierElite || cliOutput.Performance.ChangeFailureRate != core.PerformanceTierElite {
+		t.Errorf("time to restore and change failure rate should be elite by thresholds file but %+v", cliOutput.Performance)
+	}
+	if cliOutput.Performance.Overall != core.PerformanceTierHigh {
+		t.Errorf("overall should be high but %v", cliOutput.Performance.Overall)
+	}
+}
+
+func TestDefaultAppShouldBeFailWithInvalidPerformanceThresholds(t *testing.T) {
+	thresholdsPath := filepath.Join(t.TempDir(), "thresholds.json")
+	os.WriteFile(thresholdsPath, []byte(`{"changeFailureRate": "high"}`), 0644)
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:     defaltApp.Flags,
+		Action:    defaltApp.Action,
+		Writer:    output,
+		ErrWriter: bytes.NewBuffer([]byte{}),
+	}
+
+	err := testApp.Run([]string{"four-keys", "--repository", repositoryPath, "--performanceThresholds", thresholdsPath})
+	if err == nil || !strings.Contains(err.Error(), "[invalid performanceThresholds]") {
+		t.Errorf("invalid thresholds file should return error of --performanceThresholds but %v", err)
+	}
+}
+
+func TestDefaultAppShouldReturnMetricsOfEachComponent(t *testing.T) {
+	directory := t.TempDir()
+	_, err := util.NewRepositoryBuilderOnDisk(directory).GitGraph(`gitGraph
+  commit id: "w0" date: "2023-04-01" tag: "web/v0.9.0"
+  commit id: "p1" date: "2023-04-01" tag: "payments/v1.0.0"
+  commit id: "w1" date: "2023-04-02" tag: "web/v1.0.0"
+  commit id: "p2" date: "2023-04-10" tag: "payments/v1.1.0"
+  commit id: "w2" msg: "hotfix: web" date: "2023-04-12" tag: "web/v1.0.1"
+`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := bytes.NewBuffer([]byte{})
+	defaltApp := DefaultApp("")
+	testApp := &cli.App{
+		Flags:  defaltApp.Flags,
+		Action: defaltApp.Action,
+		Writer: output,
+	}
+
+	err = testApp.Run([]string{"four-keys", "--repository", directory, "--since", "2023-03-31", "--until", "2023-04-30", "--componentPattern", "^([^/]+)/"})
+	if err != nil {
+		t.Error(err)
+	}
+	var cliOutput DefaultCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Components) != 2 || cliOutput.Components[0].Component != "payments" || cliOutput.Components[1].Component != "web" {
+		t.Fatalf("components should be payments and web but %v", cliOutput.Components)
+	}
+	// web/v1.0.0 is the only failure of 5 releases and it is restored by web/v1.0.1 in 10 days
+	util.AssertIsNearBy(t, cliOutput.ChangeFailureRate, 0.2, 0.01)
+	util.AssertIsNearBy(t, cliOutput.TimeToRestore.Present(), 10, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Components[0].ChangeFailureRate, 0, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Components[1].ChangeFailureRate, 0.3333333333333333, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Components[1].TimeToRestore.Present(), 10, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Components[0].DeploymentFrequency+cliOutput.Components[1].DeploymentFrequency, cliOutput.DeploymentFrequency, 0.01)
+}
@@ -11,29 +11,64 @@ import (
 type ReleasesCliOutput struct {
 	Option   *core.Option        `json:"option"`
 	Releases []*ReleaseCliOutput `json:"releases"`
+	// Components are releases of each component in monorepo mode
+	Components []ComponentReleasesCliOutput `json:"components,omitempty"`
+}
+
+type ComponentReleasesCliOutput struct {
+	Component string              `json:"component"`
+	Releases  []*ReleaseCliOutput `json:"releases"`
 }
 
 type ReleaseCliOutput struct {
-	Tag                string                 `json:"tag"`
-	Date               time.Time              `json:"date"`
-	LeadTimeForChanges DurationWithTimeUnit   `json:"leadTimeForChanges"`
-	Result             ReleaseResultCliOutput `json:"result"`
+	Tag                           string                                 `json:"tag"`
+	Component                     string                                 `json:"component,omitempty"`
+	Date                          time.Time                              `json:"date"`
+	LeadTimeForChanges            DurationWithTimeUnit                   `json:"leadTimeForChanges"`
+	LeadTimeForChangesPercentiles LeadTimeForChangesPercentilesCliOutput `json:"leadTimeForChangesPercentiles"`
+	// WorkingLeadTimeForChanges is mean of lead time for changes in working time. It is present only if the working calendar is specified
+	WorkingLeadTimeForChanges *DurationWithTimeUnit  `json:"workingLeadTimeForChanges,omitempty"`
+	Result                    ReleaseResultCliOutput `json:"result"`
+	IsRework                  bool                   `json:"isRework"`
+	// IsOutlier is true if lead time for changes or time to restore of the release is excluded or winsorized as outlier in metrics
+	IsOutlier bool `json:"isOutlier"`
+	// BatchSize is present only if --batchSize
+	BatchSize *core.BatchSize `json:"batchSize,omitempty"`
+	// Commits are commits shipped by the release. It is empty unless --withCommits
+	Commits []CommitCliOutput `json:"commits,omitempty"`
+}
+
+type CommitCliOutput struct {
+	Hash               string               `json:"hash"`
+	Author             string               `json:"author"`
+	AuthorEmail        string               `json:"authorEmail"`
+	AuthorTime         time.Time            `json:"authorTime"`
+	CommitterTime      time.Time            `json:"committerTime"`
+	Subject            string               `json:"subject"`
+	LeadTimeForChanges DurationWithTimeUnit `json:"leadTimeForChanges"`
+	IsFix              bool                 `json:"isFix"`
 }
 
 type ReleaseResultCliOutput struct {
-	IsSuccess     bool                  `json:"isSuccess"`
+	IsSuccess bool `json:"isSuccess"`
+	// TimeToRestore is the time to restore of the failure that the release restored
 	TimeToRestore *DurationWithTimeUnit `json:"timeToRestore"`
+	// Restorations are restorations attributed to the release. Restorations by incident resolutions are attributed to the failed release
+	Restorations []RestorationCliOutput `json:"restorations"`
 }
 
 func GetCommandReleases() *cli.Command {
 	return &cli.Command{
 		Name:  "releases",
 		Usage: "list releases",
-		Flags: getCommandReleasesFlags(),
+		Flags: append(getCommandReleasesFlags(), &cli.BoolFlag{
+			Name:  "withCommits",
+			Usage: "output commits shipped by each release",
+		}),
 		Action: func(ctx *cli.Context) error {
 			context := &CliContextWrapper{context: ctx}
 			context.Debugln("In debug mode")
-			releases, err := QueryReleases(context)
+			releases, components, err := QueryReleases(context)
 			if err != nil {
 				context.Error(err)
 				return err
@@ -46,7 +81,13 @@ func GetCommandReleases() *cli.Command {
 
 			output := &ReleasesCliOutput{
 				Option:   option,
-				Releases: mapReleasesToCliOutput(releases),
+				Releases: mapReleasesToCliOutput(releases, option, ctx.Bool("withCommits")),
+			}
+			for _, component := range components {
+				output.Components = append(output.Components, ComponentReleasesCliOutput{
+					Component: component.Component,
+					Releases:  mapReleasesToCliOutput(component.Releases, option, ctx.Bool("withCommits")),
+				})
 			}
 			releasesJson, err := json.Marshal(output)
 			if err != nil {
@@ -60,43 +101,88 @@ func GetCommandReleases() *cli.Command {
 	}
 }
 
-func QueryReleases(context *CliContextWrapper) ([]*core.Release, error) {
+// QueryReleases returns releases of the repository.
+// In monorepo mode, it also returns releases of each component and the releases are aggregate of them.
+func QueryReleases(context *CliContextWrapper) ([]*core.Release, []core.ComponentReleases, error) {
 	repository, err := context.Repository()
 	if err != nil {
 		context.Error(err)
-		return nil, err
+		return nil, nil, err
 	}
 
 	option, err := context.Option()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return core.QueryReleases(repository, option), nil
+	option.ReleaseCache = context.ReleaseCache(repositoryIdentity(repository, context.context.String("repository")))
+	defer context.SaveReleaseCache(option.ReleaseCache)
+	if option.ComponentPattern == nil {
+		return core.QueryReleases(repository, option), nil, nil
+	}
+	components := core.QueryComponentReleases(repository, option)
+	return core.AggregateComponentReleases(components), components, nil
 }
 
-func mapReleasesToCliOutput(releases []*core.Release) []*ReleaseCliOutput {
+func mapReleasesToCliOutput(releases []*core.Release, option *core.Option, withCommits bool) []*ReleaseCliOutput {
 	output := make([]*ReleaseCliOutput, 0)
 	for _, release := range releases {
-		output = append(output, &ReleaseCliOutput{
-			Tag:                release.Tag,
-			Date:               release.Date,
-			LeadTimeForChanges: getDurationWithTimeUnit(release.LeadTimeForChanges),
-			Result:             mapReleaseResultToCliOutput(release.Result),
-		})
+		// values of each release are not treated as outliers, and the release is flagged instead
+		leadTimeForChangesStatistics := core.NewLeadTimeForChangesStatistics(release.LeadTimesForChanges(option.LeadTimeMode))
+		releaseOutput := &ReleaseCliOutput{
+			Tag:                           release.Tag,
+			Component:                     release.Component,
+			Date:                          release.Date,
+			LeadTimeForChanges:            getDurationWithTimeUnit(leadTimeForChangesStatistics.Mean),
+			LeadTimeForChangesPercentiles: mapLeadTimeForChangesPercentilesToCliOutput(leadTimeForChangesStatistics),
+			Result:                        mapReleaseResultToCliOutput(release),
+			IsRework:                      release.IsRework,
+			IsOutlier:                     release.IsOutlier,
+		}
+		if option.CountsChangedLines {
+			batchSize := release.BatchSize
+			releaseOutput.BatchSize = &batchSize
+		}
+		if option.WorkingCalendar != nil {
+			workingLeadTimeForChanges := getDurationWithTimeUnit(core.NewLeadTimeForChangesStatistics(release.WorkingLeadTimesForChanges(option.LeadTimeMode, option.WorkingCalendar)).Mean)
+			releaseOutput.WorkingLeadTimeForChanges = &workingLeadTimeForChanges
+		}
+		if withCommits {
+			releaseOutput.Commits = mapCommitsToCliOutput(release, option)
+		}
+		output = append(output, releaseOutput)
 	}
 	return output
 }
 
-func mapReleaseResultToCliOutput(result core.ReleaseResult) ReleaseResultCliOutput {
-	if result.TimeToRestore == nil {
-		return ReleaseResultCliOutput{
-			IsSuccess:     result.IsSuccess,
-			TimeToRestore: nil,
+func mapCommitsToCliOutput(release *core.Release, option *core.Option) []CommitCliOutput {
+	output := make([]CommitCliOutput, 0)
+	for _, commit := range release.Commits() {
+		authorTime, committerTime := commit.AuthorWhen, commit.CommitterWhen
+		if option.Location != nil {
+			authorTime, committerTime = authorTime.In(option.Location), committerTime.In(option.Location)
 		}
+		output = append(output, CommitCliOutput{
+			Hash:               commit.Hash,
+			Author:             commit.AuthorName,
+			AuthorEmail:        commit.AuthorEmail,
+			AuthorTime:         authorTime,
+			CommitterTime:      committerTime,
+			Subject:            commit.Subject(),
+			LeadTimeForChanges: getDurationWithTimeUnit(release.LeadTimeForChangesOf(commit)),
+			IsFix:              option.IsFixCommit(commit),
+		})
+	}
+	return output
+}
+
+func mapReleaseResultToCliOutput(release *core.Release) ReleaseResultCliOutput {
+	output := ReleaseResultCliOutput{
+		IsSuccess:    release.Result.IsSuccess,
+		Restorations: mapRestorationsToCliOutput(release.Restorations()),
 	}
-	t := getDurationWithTimeUnit(*result.TimeToRestore)
-	return ReleaseResultCliOutput{
-		IsSuccess:     result.IsSuccess,
-		TimeToRestore: &t,
+	if release.Result.TimeToRestore != nil {
+		timeToRestore := getDurationWithTimeUnit(*release.Result.TimeToRestore)
+		output.TimeToRestore = &timeToRestore
 	}
+	return output
 }
@@ -3,11 +3,14 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/hmiyado/four-keys/internal/core"
 	"github.com/urfave/cli/v2"
@@ -37,15 +40,132 @@ func getCommandReleasesFlags() []cli.Flag {
 			DefaultText: "now",
 			Layout:      "2006-01-02",
 		},
+		&cli.StringFlag{
+			Name:        "timezone",
+			Usage:       "IANA timezone name (e.g. Asia/Tokyo) for since, until, time series intervals and release dates",
+			DefaultText: "UTC for since and until, and offset of each commit for release dates",
+		},
 		&cli.StringFlag{
 			Name:  "ignorePattern",
 			Usage: "ignore releases that matches the pattern(regex)",
 		},
+		&cli.StringFlag{
+			Name:        "tagPattern",
+			Usage:       "include only tags that match the pattern(regex)",
+			DefaultText: "all tags",
+		},
+		&cli.StringFlag{
+			Name:        "releaseOrder",
+			Usage:       "how to order tags to find the previous release: date (committer date), semver (semantic version precedence. tags that are not semantic version are excluded)",
+			DefaultText: string(core.ReleaseOrderDate),
+		},
+		&cli.StringFlag{
+			Name:        "prereleases",
+			Usage:       "how to handle pre-release tags (e.g. v2.0.0-rc.1): include (regarded as releases), exclude (not regarded as releases and their commits are not counted), collapse (folded into the subsequent final release)",
+			DefaultText: string(core.PrereleaseModeInclude),
+		},
+		&cli.StringFlag{
+			Name:  "componentPattern",
+			Usage: "enable monorepo mode. the first group of the pattern(regex) is the component of each tag (e.g. \"^([^/]+)/\" for payments/v1.4.0). releases and four keys are calculated for each component",
+		},
+		&cli.StringSliceFlag{
+			Name:        "path",
+			Usage:       "use only commits that touch files of the path(glob) for lead time and failures (repeatable). \"**\" matches any directories",
+			DefaultText: "all files",
+		},
 		&cli.StringFlag{
 			Name:        "fixCommitPattern",
 			Usage:       "commit that message matches fixCommitPattern is regarded fix commit",
 			DefaultText: "hotfix",
 		},
+		&cli.StringFlag{
+			Name:        "leadTimeMode",
+			Usage:       "how to measure lead time for changes: first-commit (from the oldest commit of each release), per-commit (from each commit)",
+			DefaultText: string(core.LeadTimeModeFirstCommit),
+		},
+		&cli.BoolFlag{
+			Name:  "excludeInitialRelease",
+			Usage: "exclude lead time for changes of the initial release, which is measured from the first commit of the repository",
+		},
+		&cli.StringFlag{
+			Name:        "outliers",
+			Usage:       "how to treat outliers of lead time for changes and time to restore beyond Q1 - N×IQR and Q3 + N×IQR: none (use as they are), winsorize (replace with the nearest fence), drop (exclude)",
+			DefaultText: string(core.OutlierModeNone),
+		},
+		&cli.Float64Flag{
+			Name:        "outlierMultiplier",
+			Usage:       "N of the fences of outliers",
+			DefaultText: "1.5",
+		},
+		&cli.StringFlag{
+			Name:        "releaseSource",
+			Usage:       "what is regarded as a release: tag (each tag), branch (each merge commit on releaseBranch), deployment (each successful deployment of deployments)",
+			DefaultText: string(core.ReleaseSourceTag),
+		},
+		&cli.StringFlag{
+			Name:  "releaseBranch",
+			Usage: "the branch whose first-parent merge commits are regarded as releases. required when releaseSource is branch",
+		},
+		&cli.StringFlag{
+			Name:  "deployments",
+			Usage: "deployment event log file (CSV or JSON Lines). each successful deployment is regarded as a release. releaseSource defaults to deployment with it",
+		},
+		&cli.StringFlag{
+			Name:        "environment",
+			Usage:       "use only deployments to the environment. required when deployments have multiple environments",
+			DefaultText: "the only environment of deployments",
+		},
+		&cli.StringFlag{
+			Name:  "incidents",
+			Usage: "incident file (YAML or JSON) with start, resolved, release (tag or commit hash) and severity of each incident",
+		},
+		&cli.StringSliceFlag{
+			Name:        "incidentSeverity",
+			Usage:       "use only incidents of the severity (repeatable)",
+			DefaultText: "all severities",
+		},
+		&cli.StringFlag{
+			Name:        "failureSource",
+			Usage:       "what is used to detect failure of releases: commits (failureDetector), incidents, combined",
+			DefaultText: "incidents if incidents is specified, otherwise commits",
+		},
+		&cli.StringSliceFlag{
+			Name:        "failureDetector",
+			Usage:       "how to detect failure of releases from commits and tags (repeatable): fixCommit (next release has a commit that matches fixCommitPattern), conventional (next release has a conventional commit of fix type), revert (a commit of the release is reverted later), tagSuffix (tag of next release ends with fixTagSuffix)",
+			DefaultText: failureDetectorFixCommit,
+		},
+		&cli.StringFlag{
+			Name:  "fixTagSuffix",
+			Usage: "release whose tag ends with fixTagSuffix is regarded fix release. required when failureDetector includes tagSuffix",
+		},
+		&cli.StringFlag{
+			Name:        "performanceThresholds",
+			Usage:       "file (YAML or JSON) of thresholds to classify four keys into elite, high, medium and low",
+			DefaultText: "bands of Accelerate State of DevOps 2021",
+		},
+		&cli.StringSliceFlag{
+			Name:        "workingDays",
+			Usage:       "weekdays (e.g. monday) of the working calendar (repeatable). lead time for changes and time to restore are also measured in working time if any of workingDays, workingHours and holidays is specified",
+			DefaultText: "monday to friday",
+		},
+		&cli.StringFlag{
+			Name:        "workingHours",
+			Usage:       "working hours of the working calendar in HH:MM-HH:MM (e.g. 09:00-18:00)",
+			DefaultText: "whole day",
+		},
+		&cli.StringFlag{
+			Name:  "holidays",
+			Usage: "file (YAML or JSON) of a list of holidays (2006-01-02) of the working calendar",
+		},
+		&cli.BoolFlag{
+			Name:  "batchSize",
+			Usage: "output batch size (commits, authors, changed files and lines) of releases. the diff of each release is read to count changed files and lines",
+		},
+		&cli.BoolFlag{
+			Name:        "noCache",
+			Usage:       "do not use the cache of commits of releases. history of all releases is walked",
+			DefaultText: "cache in $XDG_CACHE_HOME/four-keys",
+		},
 		&cli.BoolFlag{
 			Name:  "debug",
 			Usage: "show debug message",
@@ -59,23 +179,54 @@ func onUsageError(cCtx *cli.Context, err error, isSubcommand bool) error {
 
 var timerMap map[string]time.Time
 
-func (c *CliContextWrapper) Since() time.Time {
+// Since returns the start of --since in location, or 1 month ago.
+func (c *CliContextWrapper) Since(location *time.Location) time.Time {
 	optionSince := c.context.Timestamp("since")
 	if optionSince != nil {
-		return *optionSince
+		return inLocation(*optionSince, location)
 	} else {
-		now := time.Now()
+		now := nowInLocation(location)
 		return time.Date(now.Year(), now.Month()-1, now.Day(), now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), now.Location())
 	}
 }
 
-func (c *CliContextWrapper) Until() time.Time {
+// Until returns the end of --until in location, or now.
+func (c *CliContextWrapper) Until(location *time.Location) time.Time {
 	optionSince := c.context.Timestamp("until")
 	if optionSince != nil {
-		return (*optionSince).AddDate(0, 0, 1).Add(-time.Second)
+		return inLocation(*optionSince, location).AddDate(0, 0, 1).Add(-time.Second)
 	} else {
+		return nowInLocation(location)
+	}
+}
+
+// inLocation returns the same wall clock of date in location.
+// It returns date as is if location is nil.
+func inLocation(date time.Time, location *time.Location) time.Time {
+	if location == nil {
+		return date
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), location)
+}
+
+func nowInLocation(location *time.Location) time.Time {
+	if location == nil {
 		return time.Now()
 	}
+	return time.Now().In(location)
+}
+
+// Timezone returns the location of --timezone, or nil if it is not set.
+func (c *CliContextWrapper) Timezone() (*time.Location, error) {
+	name := c.context.String("timezone")
+	if name == "" {
+		return nil, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unavailable timezone \"%s\". timezone should be IANA timezone name such as Asia/Tokyo", name)
+	}
+	return location, nil
 }
 
 func (c *CliContextWrapper) IgnorePattern() (*regexp.Regexp, error) {
@@ -86,6 +237,50 @@ func (c *CliContextWrapper) IgnorePattern() (*regexp.Regexp, error) {
 	return regexp.Compile(pattern)
 }
 
+func (c *CliContextWrapper) TagPattern() (*regexp.Regexp, error) {
+	pattern := c.context.String("tagPattern")
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func (c *CliContextWrapper) ReleaseOrder() (core.ReleaseOrder, error) {
+	orderString := c.context.String("releaseOrder")
+	if orderString == "" {
+		return core.ReleaseOrderDate, nil
+	}
+	validOrders := []core.ReleaseOrder{core.ReleaseOrderDate, core.ReleaseOrderSemver}
+	for _, order := range validOrders {
+		if orderString == string(order) {
+			return order, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable releaseOrder \"%s\". releaseOrder should be one of %s", orderString, validOrders)
+}
+
+func (c *CliContextWrapper) Prereleases() (core.PrereleaseMode, error) {
+	modeString := c.context.String("prereleases")
+	if modeString == "" {
+		return core.PrereleaseModeInclude, nil
+	}
+	validModes := []core.PrereleaseMode{core.PrereleaseModeInclude, core.PrereleaseModeExclude, core.PrereleaseModeCollapse}
+	for _, mode := range validModes {
+		if modeString == string(mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable prereleases \"%s\". prereleases should be one of %s", modeString, validModes)
+}
+
+func (c *CliContextWrapper) ComponentPattern() (*regexp.Regexp, error) {
+	pattern := c.context.String("componentPattern")
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
 func (c *CliContextWrapper) FixCommitPattern() (*regexp.Regexp, error) {
 	pattern := c.context.String("fixCommitPattern")
 	if pattern == "" {
@@ -94,13 +289,254 @@ func (c *CliContextWrapper) FixCommitPattern() (*regexp.Regexp, error) {
 	return regexp.Compile(pattern)
 }
 
+func (c *CliContextWrapper) LeadTimeMode() (core.LeadTimeMode, error) {
+	modeString := c.context.String("leadTimeMode")
+	if modeString == "" {
+		return core.LeadTimeModeFirstCommit, nil
+	}
+	validModes := []core.LeadTimeMode{core.LeadTimeModeFirstCommit, core.LeadTimeModePerCommit}
+	for _, mode := range validModes {
+		if modeString == string(mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable leadTimeMode \"%s\". leadTimeMode should be one of %s", modeString, validModes)
+}
+
+func (c *CliContextWrapper) Outliers() (core.OutlierMode, error) {
+	modeString := c.context.String("outliers")
+	if modeString == "" {
+		return core.OutlierModeNone, nil
+	}
+	validModes := []core.OutlierMode{core.OutlierModeNone, core.OutlierModeWinsorize, core.OutlierModeDrop}
+	for _, mode := range validModes {
+		if modeString == string(mode) {
+			return mode, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable outliers \"%s\". outliers should be one of %s", modeString, validModes)
+}
+
+// OutlierMultiplier returns --outlierMultiplier, or core.DefaultOutlierMultiplier if it is not set.
+func (c *CliContextWrapper) OutlierMultiplier() (float64, error) {
+	if !c.context.IsSet("outlierMultiplier") {
+		return core.DefaultOutlierMultiplier, nil
+	}
+	multiplier := c.context.Float64("outlierMultiplier")
+	if multiplier <= 0 {
+		return 0, fmt.Errorf("unavailable outlierMultiplier \"%v\". outlierMultiplier should be positive", multiplier)
+	}
+	return multiplier, nil
+}
+
+func (c *CliContextWrapper) ReleaseSource() (core.ReleaseSourceKind, error) {
+	sourceString := c.context.String("releaseSource")
+	if sourceString == "" {
+		return core.ReleaseSourceTag, nil
+	}
+	validSources := []core.ReleaseSourceKind{core.ReleaseSourceTag, core.ReleaseSourceBranch, core.ReleaseSourceDeployment}
+	for _, source := range validSources {
+		if sourceString == string(source) {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable releaseSource \"%s\". releaseSource should be one of %s", sourceString, validSources)
+}
+
+func (c *CliContextWrapper) FailureSource() (core.FailureSource, error) {
+	sourceString := c.context.String("failureSource")
+	if sourceString == "" {
+		if c.context.String("incidents") != "" {
+			return core.FailureSourceIncidents, nil
+		}
+		return core.FailureSourceFixCommits, nil
+	}
+	validSources := []core.FailureSource{core.FailureSourceFixCommits, core.FailureSourceIncidents, core.FailureSourceCombined}
+	for _, source := range validSources {
+		if sourceString == string(source) {
+			return source, nil
+		}
+	}
+	return "", fmt.Errorf("unavailable failureSource \"%s\". failureSource should be one of %s", sourceString, validSources)
+}
+
+const (
+	failureDetectorFixCommit    = "fixCommit"
+	failureDetectorConventional = "conventional"
+	failureDetectorRevert       = "revert"
+	failureDetectorTagSuffix    = "tagSuffix"
+)
+
+func (c *CliContextWrapper) FailureDetectors(fixCommitPattern *regexp.Regexp) ([]core.FailureDetector, error) {
+	names := c.context.StringSlice("failureDetector")
+	if len(names) == 0 {
+		names = []string{failureDetectorFixCommit}
+	}
+	validNames := []string{failureDetectorFixCommit, failureDetectorConventional, failureDetectorRevert, failureDetectorTagSuffix}
+	detectors := make([]core.FailureDetector, 0)
+	for _, name := range names {
+		switch name {
+		case failureDetectorFixCommit:
+			detectors = append(detectors, core.FixCommitDetector{Pattern: fixCommitPattern})
+		case failureDetectorConventional:
+			detectors = append(detectors, core.ConventionalFixDetector{})
+		case failureDetectorRevert:
+			detectors = append(detectors, core.RevertCommitDetector{})
+		case failureDetectorTagSuffix:
+			suffix := c.context.String("fixTagSuffix")
+			if suffix == "" {
+				return nil, fmt.Errorf("fixTagSuffix is required when failureDetector includes %s", failureDetectorTagSuffix)
+			}
+			detectors = append(detectors, core.TagSuffixDetector{Suffix: suffix})
+		default:
+			return nil, fmt.Errorf("unavailable failureDetector \"%s\". failureDetector should be one of %s", name, validNames)
+		}
+	}
+	return detectors, nil
+}
+
+func (c *CliContextWrapper) PerformanceThresholds() (core.PerformanceThresholds, error) {
+	path := c.context.String("performanceThresholds")
+	if path == "" {
+		return core.DefaultPerformanceThresholds(), nil
+	}
+	thresholds, err := core.ReadPerformanceThresholds(path)
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid performanceThresholds] %v", err)
+		c.Error(wrappedError)
+		return thresholds, wrappedError
+	}
+	return thresholds, nil
+}
+
+// WorkingCalendar returns the working calendar in location, or nil if none of workingDays, workingHours and holidays is set.
+func (c *CliContextWrapper) WorkingCalendar(location *time.Location) (*core.WorkingCalendar, error) {
+	workingDays := c.context.StringSlice("workingDays")
+	workingHours := c.context.String("workingHours")
+	holidaysPath := c.context.String("holidays")
+	if len(workingDays) == 0 && workingHours == "" && holidaysPath == "" {
+		return nil, nil
+	}
+	calendar := core.NewWorkingCalendar()
+	calendar.Location = location
+	if len(workingDays) > 0 {
+		calendar.WorkingDays = make([]time.Weekday, 0, len(workingDays))
+		for _, name := range workingDays {
+			weekday, ok := parseWeekday(name)
+			if !ok {
+				return nil, fmt.Errorf("unavailable workingDays \"%s\". workingDays should be weekday names such as monday", name)
+			}
+			calendar.WorkingDays = append(calendar.WorkingDays, weekday)
+		}
+	}
+	if workingHours != "" {
+		start, end, ok := parseWorkingHours(workingHours)
+		if !ok {
+			return nil, fmt.Errorf("unavailable workingHours \"%s\". workingHours should be HH:MM-HH:MM such as 09:00-18:00", workingHours)
+		}
+		calendar.WorkingHoursStart, calendar.WorkingHoursEnd = start, end
+	}
+	if holidaysPath != "" {
+		holidays, err := core.ReadHolidays(holidaysPath)
+		if err != nil {
+			return nil, err
+		}
+		calendar.Holidays = holidays
+	}
+	return calendar, nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
+
+// parseWorkingHours parses HH:MM-HH:MM into offsets from midnight. The end should be after the start and until 24:00.
+func parseWorkingHours(workingHours string) (time.Duration, time.Duration, bool) {
+	parseClock := func(clock string) (time.Duration, bool) {
+		var hour, minute int
+		if _, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil || hour < 0 || minute < 0 || minute >= 60 {
+			return 0, false
+		}
+		return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+	}
+	clocks := strings.Split(workingHours, "-")
+	if len(clocks) != 2 {
+		return 0, 0, false
+	}
+	start, okStart := parseClock(clocks[0])
+	end, okEnd := parseClock(clocks[1])
+	if !okStart || !okEnd || end <= start || end > 24*time.Hour {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// ReleaseCache opens the release cache of the repository identified by repositoryIdentity.
+// It returns nil with --noCache, or if the cache directory is not available.
+func (c *CliContextWrapper) ReleaseCache(repositoryIdentity string) *core.ReleaseCache {
+	if c.context.Bool("noCache") {
+		return nil
+	}
+	directory, err := core.DefaultReleaseCacheDirectory()
+	if err != nil {
+		c.Debugln("Release cache is disabled:", err)
+		return nil
+	}
+	return core.OpenReleaseCache(directory, repositoryIdentity)
+}
+
+// SaveReleaseCache saves cache if it is not nil. Failure of saving is not an error because the cache is optional.
+func (c *CliContextWrapper) SaveReleaseCache(cache *core.ReleaseCache) {
+	if cache == nil {
+		return
+	}
+	if err := cache.Save(); err != nil {
+		c.Debugln("Cannot save release cache", cache.Path(), ":", err)
+	}
+}
+
+// repositoryIdentity returns url of the repository, or the absolute path of the local repository if url is empty.
+// The path of a bare repository is its git directory.
+func repositoryIdentity(repository *git.Repository, url string) string {
+	if url != "" {
+		return url
+	}
+	root := ""
+	if worktree, err := repository.Worktree(); err == nil && worktree.Filesystem != nil {
+		root = worktree.Filesystem.Root()
+	} else if storage, ok := repository.Storer.(*filesystem.Storage); ok {
+		root = storage.Filesystem().Root()
+	} else {
+		return ""
+	}
+	path, err := filepath.Abs(root)
+	if err != nil {
+		return root
+	}
+	return path
+}
+
 func (c *CliContextWrapper) Repository() (*git.Repository, error) {
 	c.StartTimer("Open Repository")
 	defer c.StopTimer("Open Repository")
 	repositoryUrl := c.context.String("repository")
-	accessToken := c.context.String("accessToken")
-	var repository *git.Repository
-	var error error
+	if repositoryUrl == "" {
+		repository, error := git.PlainOpenWithOptions("./", &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: false})
+		if error != nil {
+			return nil, errors.New("cannot open repository at current directory")
+		}
+		return repository, nil
+	}
+	return cloneRepository(repositoryUrl, c.context.String("accessToken"))
+}
+
+// cloneRepository clones the repository of url in memory.
+func cloneRepository(repositoryUrl string, accessToken string) (*git.Repository, error) {
 	var auth *http.BasicAuth
 	if accessToken != "" {
 		auth = &http.BasicAuth{
@@ -108,21 +544,13 @@ func (c *CliContextWrapper) Repository() (*git.Repository, error) {
 			Password: accessToken,
 		}
 	}
-	if repositoryUrl == "" {
-		repository, error = git.PlainOpenWithOptions("./", &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: false})
-		if error != nil {
-			return nil, errors.New("cannot open repository at current directory")
-		}
-	} else {
-		repository, error = git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-			Auth: auth,
-			URL:  repositoryUrl,
-		})
-		if error != nil {
-			return nil, fmt.Errorf("cannot clone repository: %v", repositoryUrl)
-		}
+	repository, error := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
+		Auth: auth,
+		URL:  repositoryUrl,
+	})
+	if error != nil {
+		return nil, fmt.Errorf("cannot clone repository: %v", repositoryUrl)
 	}
-
 	return repository, nil
 }
 
@@ -134,6 +562,34 @@ func (c *CliContextWrapper) Option() (*core.Option, error) {
 		return nil, wrappedError
 	}
 
+	tagPattern, err := c.TagPattern()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid tagPattern] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	releaseOrder, err := c.ReleaseOrder()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid releaseOrder] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	prereleases, err := c.Prereleases()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid prereleases] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	componentPattern, err := c.ComponentPattern()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid componentPattern] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
 	fixCommitPattern, err := c.FixCommitPattern()
 	if err != nil {
 		wrappedError := fmt.Errorf("[invalid fixCommitPattern] %v", err)
@@ -141,14 +597,135 @@ func (c *CliContextWrapper) Option() (*core.Option, error) {
 		return nil, wrappedError
 	}
 
+	failureDetectors, err := c.FailureDetectors(fixCommitPattern)
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid failureDetector] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	leadTimeMode, err := c.LeadTimeMode()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid leadTimeMode] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	outliers, err := c.Outliers()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid outliers] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	outlierMultiplier, err := c.OutlierMultiplier()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid outlierMultiplier] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	releaseSource, err := c.ReleaseSource()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid releaseSource] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+	releaseBranch := c.context.String("releaseBranch")
+	if releaseSource == core.ReleaseSourceBranch && releaseBranch == "" {
+		wrappedError := fmt.Errorf("[invalid releaseBranch] releaseBranch is required when releaseSource is %s", releaseSource)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	var deployments []core.Deployment
+	if deploymentsPath := c.context.String("deployments"); deploymentsPath != "" {
+		deployments, err = core.ReadDeployments(deploymentsPath)
+		if err != nil {
+			wrappedError := fmt.Errorf("[invalid deployments] %v", err)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+		if c.context.IsSet("releaseSource") && releaseSource != core.ReleaseSourceDeployment {
+			wrappedError := fmt.Errorf("[invalid releaseSource] releaseSource %s cannot be used with deployments", releaseSource)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+		// the same commit deployed to each environment would be counted as multiple releases
+		if environments := core.DeploymentEnvironments(deployments); c.context.String("environment") == "" && len(environments) > 1 {
+			wrappedError := fmt.Errorf("[invalid environment] environment is required because deployments have environments %q", environments)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+		releaseSource = core.ReleaseSourceDeployment
+	} else if releaseSource == core.ReleaseSourceDeployment {
+		wrappedError := fmt.Errorf("[invalid deployments] deployments is required when releaseSource is %s", releaseSource)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	failureSource, err := c.FailureSource()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid failureSource] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+	var incidents []core.Incident
+	if incidentsPath := c.context.String("incidents"); incidentsPath != "" {
+		incidents, err = core.ReadIncidents(incidentsPath)
+		if err != nil {
+			wrappedError := fmt.Errorf("[invalid incidents] %v", err)
+			c.Error(wrappedError)
+			return nil, wrappedError
+		}
+	} else if failureSource != core.FailureSourceFixCommits {
+		wrappedError := fmt.Errorf("[invalid incidents] incidents is required when failureSource is %s", failureSource)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	location, err := c.Timezone()
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid timezone] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
+	workingCalendar, err := c.WorkingCalendar(location)
+	if err != nil {
+		wrappedError := fmt.Errorf("[invalid workingCalendar] %v", err)
+		c.Error(wrappedError)
+		return nil, wrappedError
+	}
+
 	return &core.Option{
-		Since:             c.Since(),
-		Until:             c.Until(),
-		IgnorePattern:     ignorePattern,
-		IsLocalRepository: c.context.String("repository") == "",
-		FixCommitPattern:  fixCommitPattern,
-		StartTimerFunc:    c.StartTimer,
-		StopTimerFunc:     c.StopTimer,
-		DebuglnFunc:       c.Debugln,
+		Since:                 c.Since(location),
+		Until:                 c.Until(location),
+		IgnorePattern:         ignorePattern,
+		TagPattern:            tagPattern,
+		ReleaseOrder:          releaseOrder,
+		Prereleases:           prereleases,
+		ComponentPattern:      componentPattern,
+		Paths:                 c.context.StringSlice("path"),
+		IsLocalRepository:     c.context.String("repository") == "",
+		FixCommitPattern:      fixCommitPattern,
+		LeadTimeMode:          leadTimeMode,
+		ExcludeInitialRelease: c.context.Bool("excludeInitialRelease"),
+		Outliers:              outliers,
+		OutlierMultiplier:     outlierMultiplier,
+		ReleaseSource:         releaseSource,
+		ReleaseBranch:         releaseBranch,
+		Deployments:           deployments,
+		DeploymentEnvironment: c.context.String("environment"),
+		FailureSource:         failureSource,
+		Incidents:             incidents,
+		IncidentSeverities:    c.context.StringSlice("incidentSeverity"),
+		FailureDetectors:      failureDetectors,
+		Location:              location,
+		WorkingCalendar:       workingCalendar,
+		CountsChangedLines:    c.context.Bool("batchSize"),
+		StartTimerFunc:        c.StartTimer,
+		StopTimerFunc:         c.StopTimer,
+		DebuglnFunc:           c.Debugln,
 	}, nil
 }
@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/hmiyado/four-keys/internal/util"
 	"github.com/urfave/cli/v2"
 )
@@ -31,7 +34,7 @@ func TestGetCommandReleaseShouldHaveDefaultTimeRangeOption(t *testing.T) {
 	output := bytes.NewBuffer([]byte{})
 	app := &cli.App{Writer: output}
 	set := flag.NewFlagSet("test", 0)
-	_ = set.Parse([]string{"releases", "--repository", "https://github.com/go-git/go-git"})
+	_ = set.Parse([]string{"releases", "--repository", repositoryPath})
 
 	cCtx := cli.NewContext(app, set, nil)
 	GetCommandReleases().Run(cCtx)
@@ -54,10 +57,10 @@ func TestGetCommandReleaseShouldReturnReleasesWithRepositoryUrlSinceUntilIgnoreP
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"releases",
-		"--repository", "https://github.com/go-git/go-git",
-		"--since", "2020-01-01",
-		"--until", "2020-12-31",
-		"--ignorePattern", "v5\\.2\\.0",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--ignorePattern", "v2\\.0\\.0",
 	}
 	_ = set.Parse(args)
 
@@ -78,9 +81,9 @@ func TestGetCommandReleaseShouldHaveLeadTimeForChangesForEachReleases(t *testing
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"releases",
-		"--repository", "https://github.com/go-git/go-git",
-		"--since", "2020-01-01",
-		"--until", "2020-12-31",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
 	}
 	_ = set.Parse(args)
 
@@ -89,27 +92,24 @@ func TestGetCommandReleaseShouldHaveLeadTimeForChangesForEachReleases(t *testing
 
 	// Output:
 	// {
-	//   "option": { "since": "2020-01-01T00:00:00Z", "until": "2020-12-31T23:59:59Z" },
+	//   "option": { "since": "2023-03-31T00:00:00Z", "until": "2023-04-30T23:59:59Z" },
 	//   "releases": [
 	//     {
-	//       "tag": "v5.2.0",
-	//       "date": "2020-10-09T11:49:30+02:00",
-	//       "leadTimeForChanges": { "value": 130.77916666666667, "unit": "day" },
-	//       "result": { "isSuccess": true, "timeToRestore": null }
+	//       "tag": "v2.0.1",
+	//       "date": "2023-04-30T00:00:00Z",
+	//       "leadTimeForChanges": { "value": 5, "unit": "day" },
+	//       "result": { "isSuccess": true, "timeToRestore": { "value": 15, "unit": "day" } }
 	//     },
 	//     {
-	//       "tag": "v5.1.0",
-	//       "date": "2020-05-24T19:25:08+02:00",
-	//       "leadTimeForChanges": { "value": 69.86515046296296, "unit": "day" },
-	//       "result": { "isSuccess": true, "timeToRestore": null }
+	//       "tag": "v2.0.0",
+	//       "date": "2023-04-15T00:00:00Z",
+	//       "leadTimeForChanges": { "value": 10, "unit": "day" },
+	//       "result": { "isSuccess": false, "timeToRestore": null }
 	//     },
 	//     {
-	//       "tag": "v5.0.0",
-	//       "date": "2020-03-15T21:18:32+01:00",
-	//       "leadTimeForChanges": {
-	//         "value": 224.73468749999998,
-	//         "unit": "day"
-	//       },
+	//       "tag": "v1.0.0",
+	//       "date": "2023-04-01T00:00:00Z",
+	//       "leadTimeForChanges": { "value": 0, "unit": "day" },
 	//       "result": { "isSuccess": true, "timeToRestore": null }
 	//     }
 	//   ]
@@ -118,11 +118,11 @@ func TestGetCommandReleaseShouldHaveLeadTimeForChangesForEachReleases(t *testing
 	json.Unmarshal(output.Bytes(), &cliOutput)
 	expectedReleasesNum := 3
 	if len(cliOutput.Releases) != expectedReleasesNum {
-		t.Errorf("releases should have %v releases but %v", expectedReleasesNum, len(cliOutput.Releases))
+		t.Fatalf("releases should have %v releases but %v", expectedReleasesNum, len(cliOutput.Releases))
 	}
-	util.AssertIsNearBy(t, cliOutput.Releases[0].LeadTimeForChanges.Present(), 130.77916666666667, 0.01)
-	util.AssertIsNearBy(t, cliOutput.Releases[1].LeadTimeForChanges.Present(), 69.86515046296296, 0.01)
-	util.AssertIsNearBy(t, cliOutput.Releases[2].LeadTimeForChanges.Present(), 224.73468749999998, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Releases[0].LeadTimeForChanges.Present(), 5, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Releases[1].LeadTimeForChanges.Present(), 10, 0.01)
+	util.AssertIsNearBy(t, cliOutput.Releases[2].LeadTimeForChanges.Present(), 0, 0.01)
 }
 
 func TestGetCommandReleaseShouldBeFailWithInvalidSince(t *testing.T) {
@@ -130,7 +130,7 @@ func TestGetCommandReleaseShouldBeFailWithInvalidSince(t *testing.T) {
 	errOutput := bytes.NewBuffer([]byte{})
 	app := &cli.App{Writer: output, ErrWriter: errOutput}
 	set := flag.NewFlagSet("test", 0)
-	args := []string{"releases", "--repository", "https://github.com/go-git/go-git", "--since", "invalidtext"}
+	args := []string{"releases", "--repository", repositoryPath, "--since", "invalidtext"}
 	_ = set.Parse(args)
 
 	cCtx := cli.NewContext(app, set, nil)
@@ -148,7 +148,7 @@ func TestGetCommandReleaseShouldBeFailWithInvalidUntil(t *testing.T) {
 	output := bytes.NewBuffer([]byte{})
 	app := &cli.App{Writer: output}
 	set := flag.NewFlagSet("test", 0)
-	args := []string{"releases", "--repository", "https://github.com/go-git/go-git", "--until", "invalidtext"}
+	args := []string{"releases", "--repository", repositoryPath, "--until", "invalidtext"}
 	_ = set.Parse(args)
 
 	cCtx := cli.NewContext(app, set, nil)
@@ -167,7 +167,7 @@ func TestGetCommandReleaseShouldBeFailWithInvalidIgnorePattern(t *testing.T) {
 	errOutput := bytes.NewBuffer([]byte{})
 	app := &cli.App{Writer: output, ErrWriter: errOutput}
 	set := flag.NewFlagSet("test", 0)
-	args := []string{"releases", "--repository", "https://github.com/go-git/go-git", "--ignorePattern", "*"}
+	args := []string{"releases", "--repository", repositoryPath, "--ignorePattern", "*"}
 	_ = set.Parse(args)
 
 	cCtx := cli.NewContext(app, set, nil)
@@ -186,7 +186,7 @@ func TestGetCommandReleaseShouldBeFailWithInvalidFixCommitPattern(t *testing.T)
 	errOutput := bytes.NewBuffer([]byte{})
 	app := &cli.App{Writer: output, ErrWriter: errOutput}
 	set := flag.NewFlagSet("test", 0)
-	args := []string{"releases", "--repository", "https://github.com/go-git/go-git", "--fixCommitPattern", "*"}
+	args := []string{"releases", "--repository", repositoryPath, "--fixCommitPattern", "*"}
 	_ = set.Parse(args)
 
 	cCtx := cli.NewContext(app, set, nil)
@@ -212,3 +212,674 @@ func TestGetCommandReleaseShouldBeDebuggable(t *testing.T) {
 		t.Errorf("--debug is not available log: %v", output.String())
 	}
 }
+
+func TestGetCommandReleaseShouldBeFailWithInvalidLeadTimeMode(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	errOutput := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: errOutput}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--leadTimeMode", "last-commit"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	error := GetCommandReleases().Run(cCtx, args...)
+
+	if error == nil {
+		t.Errorf("Invalid --leadTimeMode option does not return error. log: %v", output.String())
+	}
+	if !strings.Contains(error.Error(), "[invalid leadTimeMode]") {
+		t.Errorf("Invalid --leadTimeMode option does not return error of --leadTimeMode. error: %v", error.Error())
+	}
+}
+
+func TestGetCommandReleaseShouldHaveLeadTimeForChangesPerCommit(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-04-29",
+		"--until", "2023-04-30",
+		"--leadTimeMode", "per-commit",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 1 {
+		t.Fatalf("releases should have v2.0.1 but %v", len(cliOutput.Releases))
+	}
+	// v2.0.1 ships m3(0 days), h2(3 days) and h1(5 days)
+	release := cliOutput.Releases[0]
+	util.AssertIsNearBy(t, release.LeadTimeForChanges.Present(), 8.0/3, 0.01)
+	util.AssertIsNearBy(t, release.LeadTimeForChangesPercentiles.Median.Present(), 3, 0.01)
+	util.AssertIsNearBy(t, release.LeadTimeForChangesPercentiles.P95.Present(), 4.8, 0.01)
+}
+
+func TestGetCommandReleaseShouldBeFailWithoutReleaseBranch(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	errOutput := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: errOutput}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--releaseSource", "branch"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	error := GetCommandReleases().Run(cCtx, args...)
+
+	if error == nil {
+		t.Errorf("--releaseSource branch without --releaseBranch does not return error. log: %v", output.String())
+	}
+	if !strings.Contains(error.Error(), "[invalid releaseBranch]") {
+		t.Errorf("--releaseSource branch without --releaseBranch does not return error of --releaseBranch. error: %v", error.Error())
+	}
+}
+
+func TestGetCommandReleaseShouldUseDeploymentsAsReleases(t *testing.T) {
+	deploymentsPath := filepath.Join(t.TempDir(), "deployments.csv")
+	os.WriteFile(deploymentsPath, []byte(`tag,timestamp,environment,status
+v1.0.0,2023-04-02T00:00:00Z,production,success
+v2.0.0,2023-04-16T00:00:00Z,production,success
+v2.0.0,2023-04-15T00:00:00Z,staging,success
+`), 0644)
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--deployments", deploymentsPath,
+		"--environment", "production",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 2 {
+		t.Fatalf("releases should be deployments of v2.0.0 and v1.0.0 to production but %v", len(cliOutput.Releases))
+	}
+	expectedDate := time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC)
+	if cliOutput.Releases[0].Tag != "v2.0.0" || !cliOutput.Releases[0].Date.Equal(expectedDate) {
+		t.Errorf("v2.0.0 should be released at %v but %v at %v", expectedDate, cliOutput.Releases[0].Tag, cliOutput.Releases[0].Date)
+	}
+	// from d1 (2023-04-05) to deployment (2023-04-16)
+	util.AssertIsNearBy(t, cliOutput.Releases[0].LeadTimeForChanges.Present(), 11, 0.01)
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidDeployments(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	errOutput := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: errOutput}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--deployments", filepath.Join(t.TempDir(), "not_found.csv")}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	error := GetCommandReleases().Run(cCtx, args...)
+
+	if error == nil {
+		t.Errorf("Invalid --deployments option does not return error. log: %v", output.String())
+	}
+	if !strings.Contains(error.Error(), "[invalid deployments]") {
+		t.Errorf("Invalid --deployments option does not return error of --deployments. error: %v", error.Error())
+	}
+}
+
+func TestGetCommandReleaseShouldBeFailWithDeploymentsAndOtherReleaseSource(t *testing.T) {
+	deploymentsPath := filepath.Join(t.TempDir(), "deployments.csv")
+	os.WriteFile(deploymentsPath, []byte("tag,timestamp,environment,status\nv1.0.0,2023-04-02T00:00:00Z,production,success\n"), 0644)
+	multipleEnvironmentsPath := filepath.Join(t.TempDir(), "deployments.csv")
+	os.WriteFile(multipleEnvironmentsPath, []byte("tag,timestamp,environment,status\nv1.0.0,2023-04-01T00:00:00Z,staging,success\nv1.0.0,2023-04-02T00:00:00Z,production,success\n"), 0644)
+	testCases := []struct {
+		args          []string
+		expectedError string
+	}{
+		{[]string{"--deployments", deploymentsPath, "--releaseSource", "tag"}, "[invalid releaseSource]"},
+		{[]string{"--deployments", deploymentsPath, "--releaseSource", "branch", "--releaseBranch", "main"}, "[invalid releaseSource]"},
+		{[]string{"--releaseSource", "deployment"}, "[invalid deployments]"},
+		{[]string{"--deployments", multipleEnvironmentsPath}, "[invalid environment]"},
+	}
+	for _, testCase := range testCases {
+		app := &cli.App{Writer: bytes.NewBuffer([]byte{}), ErrWriter: bytes.NewBuffer([]byte{})}
+		set := flag.NewFlagSet("test", 0)
+		args := append([]string{"releases", "--repository", repositoryPath}, testCase.args...)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandReleases().Run(cCtx, args...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+			t.Errorf("%v should return error of %v but %v", testCase.args, testCase.expectedError, err)
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldUseFailureDetectors(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--failureDetector", "tagSuffix",
+		"--fixTagSuffix", ".0.0",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 3 {
+		t.Fatalf("releases should be v2.0.1, v2.0.0 and v1.0.0 but %v", len(cliOutput.Releases))
+	}
+	// v2.0.0 ends with .0.0 so that v1.0.0 is failure. hotfix commits of v2.0.1 are not used
+	if !cliOutput.Releases[1].Result.IsSuccess || cliOutput.Releases[2].Result.IsSuccess {
+		t.Errorf("only v1.0.0 should be failure but %v", cliOutput.Releases)
+	}
+	if cliOutput.Releases[0].IsRework || !cliOutput.Releases[1].IsRework || !cliOutput.Releases[2].IsRework {
+		t.Errorf("v2.0.0 and v1.0.0 should be rework but %v", cliOutput.Releases)
+	}
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidFailureDetector(t *testing.T) {
+	for _, args := range [][]string{
+		{"releases", "--failureDetector", "unknown"},
+		{"releases", "--failureDetector", "tagSuffix"},
+	} {
+		output := bytes.NewBuffer([]byte{})
+		errOutput := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output, ErrWriter: errOutput}
+		set := flag.NewFlagSet("test", 0)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		error := GetCommandReleases().Run(cCtx, args...)
+
+		if error == nil {
+			t.Errorf("%v does not return error. log: %v", args, output.String())
+			continue
+		}
+		if !strings.Contains(error.Error(), "[invalid failureDetector]") {
+			t.Errorf("%v does not return error of --failureDetector. error: %v", args, error.Error())
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldOrderReleasesBySemanticVersion(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-01",
+		"--until", "2023-05-31",
+		"--tagPattern", `^v2\.`,
+		"--releaseOrder", "semver",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 3 || cliOutput.Releases[0].Tag != "v2.1.0" || cliOutput.Releases[2].Tag != "v2.0.0" {
+		t.Errorf("releases should be v2.1.0, v2.0.1 and v2.0.0 but %v", cliOutput.Releases)
+	}
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidTagOptions(t *testing.T) {
+	for _, testCase := range []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"releases", "--tagPattern", "*"}, "[invalid tagPattern]"},
+		{[]string{"releases", "--releaseOrder", "name"}, "[invalid releaseOrder]"},
+		{[]string{"releases", "--prereleases", "only"}, "[invalid prereleases]"},
+	} {
+		output := bytes.NewBuffer([]byte{})
+		errOutput := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output, ErrWriter: errOutput}
+		set := flag.NewFlagSet("test", 0)
+		_ = set.Parse(testCase.args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		error := GetCommandReleases().Run(cCtx, testCase.args...)
+
+		if error == nil {
+			t.Errorf("%v does not return error. log: %v", testCase.args, output.String())
+			continue
+		}
+		if !strings.Contains(error.Error(), testCase.expected) {
+			t.Errorf("%v does not return error of %v. error: %v", testCase.args, testCase.expected, error.Error())
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidComponentPattern(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	errOutput := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: errOutput}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--componentPattern", "("}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	error := GetCommandReleases().Run(cCtx, args...)
+
+	if error == nil {
+		t.Errorf("Invalid --componentPattern option does not return error. log: %v", output.String())
+	}
+	if !strings.Contains(error.Error(), "[invalid componentPattern]") {
+		t.Errorf("Invalid --componentPattern option does not return error of --componentPattern. error: %v", error.Error())
+	}
+}
+
+func TestGetCommandReleaseShouldReturnReleasesOfEachComponent(t *testing.T) {
+	directory := t.TempDir()
+	_, err := util.NewRepositoryBuilderOnDisk(directory).GitGraph(`gitGraph
+  commit id: "p1" date: "2023-04-01" tag: "payments/v1.0.0"
+  commit id: "w1" date: "2023-04-02" tag: "web/v1.0.0"
+  commit id: "p2" date: "2023-04-10" tag: "payments/v1.1.0"
+`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", directory,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--componentPattern", "^([^/]+)/",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 3 || cliOutput.Releases[0].Component != "payments" || cliOutput.Releases[1].Component != "web" {
+		t.Errorf("releases should be aggregate of components but %v", cliOutput.Releases)
+	}
+	if len(cliOutput.Components) != 2 || len(cliOutput.Components[0].Releases) != 2 || len(cliOutput.Components[1].Releases) != 1 {
+		t.Errorf("components should have 2 releases of payments and 1 release of web but %v", cliOutput.Components)
+	}
+}
+
+func TestGetCommandReleaseShouldUseTimezone(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--timezone", "Asia/Tokyo",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandReleases().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	expectedSince := time.Date(2023, 3, 31, 0, 0, 0, 0, tokyo)
+	if !cliOutput.Option.Since.Equal(expectedSince) {
+		t.Errorf("since should be %v but %v", expectedSince, cliOutput.Option.Since)
+	}
+	if len(cliOutput.Releases) == 0 {
+		t.Fatal("releases should not be empty")
+	}
+	for _, release := range cliOutput.Releases {
+		if _, offset := release.Date.Zone(); offset != 9*60*60 {
+			t.Errorf("date of %v should be in Asia/Tokyo but %v", release.Tag, release.Date)
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidTimezone(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output, ErrWriter: bytes.NewBuffer([]byte{})}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--repository", repositoryPath, "--timezone", "Mars/Olympus"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandReleases().Run(cCtx, args...)
+
+	if err == nil || !strings.Contains(err.Error(), "[invalid timezone]") {
+		t.Errorf("releases should be fail with invalid timezone but %v", err)
+	}
+}
+
+func TestGetCommandReleaseShouldReturnCommitsOfEachRelease(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--withCommits",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandReleases().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) == 0 {
+		t.Fatal("releases should not be empty")
+	}
+	// v2.0.1 ships hotfix commits h1 and h2 with merge commit m3, and only h1 has "hotfix" in its message
+	hotfixRelease := cliOutput.Releases[0]
+	subjects := make([]string, 0)
+	for _, commit := range hotfixRelease.Commits {
+		subjects = append(subjects, commit.Subject)
+		if commit.Hash == "" || commit.Author == "" || commit.CommitterTime.IsZero() {
+			t.Errorf("commit should have hash, author and committer time but %+v", commit)
+		}
+		if !commit.CommitterTime.Add(*commit.LeadTimeForChanges.Duration).Equal(hotfixRelease.Date) {
+			t.Errorf("lead time of %v should be from committer time to the release date but %v", commit.Subject, commit.LeadTimeForChanges.Present())
+		}
+		if commit.IsFix != strings.HasPrefix(commit.Subject, "hotfix") {
+			t.Errorf("only hotfix commit should be fix but %+v", commit)
+		}
+	}
+	if strings.Join(subjects, ",") != "m3,h2,hotfix: h1" {
+		t.Errorf("v2.0.1 should ship m3, h2 and h1 but %v", subjects)
+	}
+}
+
+func TestGetCommandReleaseShouldNotReturnCommitsByDefault(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	for _, release := range cliOutput.Releases {
+		if release.Commits != nil {
+			t.Errorf("releases should not have commits without --withCommits but %v has %v", release.Tag, release.Commits)
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldHaveBatchSizeOfEachRelease(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--batchSize"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	expectedCommits := []int{3, 3, 1}
+	if len(cliOutput.Releases) != len(expectedCommits) {
+		t.Fatalf("releases should be v2.0.1, v2.0.0 and v1.0.0 but %v", cliOutput.Releases)
+	}
+	for i, release := range cliOutput.Releases {
+		if release.BatchSize == nil || release.BatchSize.Commits != expectedCommits[i] || release.BatchSize.Authors != 1 {
+			t.Errorf("%v should have %v commits by an author but %+v", release.Tag, expectedCommits[i], release.BatchSize)
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldNotHaveBatchSizeByDefault(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{"releases", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandReleases().Run(cCtx, args...)
+
+	if strings.Contains(output.String(), "batchSize") {
+		t.Errorf("batch size should not be present without batchSize but %v", output.String())
+	}
+}
+
+func TestGetCommandReleaseShouldHaveWorkingLeadTimeForChanges(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-31",
+		"--until", "2023-04-30",
+		"--workingDays", "monday",
+		"--workingDays", "tuesday",
+		"--workingDays", "wednesday",
+		"--workingDays", "thursday",
+		"--workingDays", "friday",
+		"--workingDays", "saturday",
+		"--workingDays", "sunday",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandReleases().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) == 0 {
+		t.Fatal("releases should not be empty")
+	}
+	// every day is working day and whole day is working hours, so working time equals to wall-clock time
+	for _, release := range cliOutput.Releases {
+		if release.WorkingLeadTimeForChanges == nil {
+			t.Fatalf("%v should have working lead time for changes", release.Tag)
+		}
+		if *release.WorkingLeadTimeForChanges.Duration != *release.LeadTimeForChanges.Duration {
+			t.Errorf("working lead time for changes of %v should be %v but %v", release.Tag, release.LeadTimeForChanges.Present(), release.WorkingLeadTimeForChanges.Present())
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldFlagExcludedInitialRelease(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"releases",
+		"--repository", repositoryPath,
+		"--since", "2023-03-01",
+		"--until", "2023-04-30",
+		"--excludeInitialRelease",
+	}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandReleases().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput ReleasesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+	if len(cliOutput.Releases) != 4 {
+		t.Fatalf("releases should be v2.0.1, v2.0.0, v1.0.0 and v0.1.0 but %v", cliOutput.Releases)
+	}
+	for _, release := range cliOutput.Releases {
+		if release.IsOutlier != (release.Tag == "v0.1.0") {
+			t.Errorf("only the initial release v0.1.0 should be outlier but %v is %v", release.Tag, release.IsOutlier)
+		}
+	}
+	// lead time of each release is kept as it is
+	util.AssertIsNearBy(t, cliOutput.Releases[3].LeadTimeForChanges.Present(), 9, 0.01)
+}
+
+func TestGetCommandReleaseShouldBeFailWithInvalidOutliers(t *testing.T) {
+	for _, testCase := range []struct {
+		args          []string
+		expectedError string
+	}{
+		{[]string{"--outliers", "trim"}, "[invalid outliers]"},
+		{[]string{"--outlierMultiplier", "0"}, "[invalid outlierMultiplier]"},
+	} {
+		app := &cli.App{Writer: bytes.NewBuffer([]byte{}), ErrWriter: bytes.NewBuffer([]byte{})}
+		set := flag.NewFlagSet("test", 0)
+		args := append([]string{"releases", "--repository", repositoryPath}, testCase.args...)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandReleases().Run(cCtx, args...)
+		if err == nil || !strings.Contains(err.Error(), testCase.expectedError) {
+			t.Errorf("%v should return error of %v but %v", testCase.args, testCase.expectedError, err)
+		}
+	}
+}
+
+func TestGetCommandReleaseShouldUseReleaseCache(t *testing.T) {
+	testCases := []struct {
+		args              []string
+		expectedCacheFile bool
+	}{
+		{[]string{"--batchSize"}, true},
+		{[]string{"--batchSize", "--noCache"}, false},
+	}
+	for _, testCase := range testCases {
+		cacheDirectory := t.TempDir()
+		t.Setenv("XDG_CACHE_HOME", cacheDirectory)
+		var outputs []ReleasesCliOutput
+		// the second run reads the cache written by the first run
+		for run := 0; run < 2; run++ {
+			output := bytes.NewBuffer([]byte{})
+			app := &cli.App{Writer: output}
+			set := flag.NewFlagSet("test", 0)
+			args := append([]string{"releases", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30"}, testCase.args...)
+			_ = set.Parse(args)
+
+			cCtx := cli.NewContext(app, set, nil)
+			err := GetCommandReleases().Run(cCtx, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var cliOutput ReleasesCliOutput
+			json.Unmarshal(output.Bytes(), &cliOutput)
+			outputs = append(outputs, cliOutput)
+		}
+
+		cacheFiles, _ := filepath.Glob(filepath.Join(cacheDirectory, "four-keys", "*.json"))
+		if (len(cacheFiles) == 1) != testCase.expectedCacheFile {
+			t.Errorf("%v: cache file should be written: %v but %v", testCase.args, testCase.expectedCacheFile, cacheFiles)
+		}
+		if len(outputs[0].Releases) != 3 || len(outputs[1].Releases) != len(outputs[0].Releases) {
+			t.Fatalf("%v: releases should be same in each run but %v and %v", testCase.args, outputs[0].Releases, outputs[1].Releases)
+		}
+		for i, release := range outputs[1].Releases {
+			expected := outputs[0].Releases[i]
+			if release.Tag != expected.Tag || *release.LeadTimeForChanges.Duration != *expected.LeadTimeForChanges.Duration || release.Result.IsSuccess != expected.Result.IsSuccess || *release.BatchSize != *expected.BatchSize {
+				t.Errorf("%v: %+v should be same as %+v", testCase.args, release, expected)
+			}
+		}
+	}
+}
+
+func TestRepositoryIdentityShouldBeDifferentForEachBareRepository(t *testing.T) {
+	directory := t.TempDir()
+	identities := make(map[string]bool)
+	for _, name := range []string{"first.git", "second.git"} {
+		repository, err := git.PlainInit(filepath.Join(directory, name), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		identity := repositoryIdentity(repository, "")
+		if !strings.HasSuffix(identity, name) {
+			t.Errorf("identity of bare repository should be its git directory %v but %v", name, identity)
+		}
+		identities[identity] = true
+	}
+	if len(identities) != 2 {
+		t.Errorf("bare repositories should not share identity but %v", identities)
+	}
+}
+
+func TestGetCommandReleaseShouldHaveRestorationsOfIncidents(t *testing.T) {
+	incidentsPath := filepath.Join(t.TempDir(), "incidents.yaml")
+	os.WriteFile(incidentsPath, []byte(`
+- start: 2023-04-16T00:00:00Z
+  resolved: 2023-04-18T00:00:00Z
+  release: v2.0.0
+  severity: major
+- start: 2023-04-19T00:00:00Z
+  resolved: 2023-04-19T06:00:00Z
+  release: v2.0.0
+  severity: minor
+`), 0644)
+	testCases := []struct {
+		args                 []string
+		expectedRestorations int
+	}{
+		{[]string{}, 2},
+		{[]string{"--incidentSeverity", "major"}, 1},
+	}
+	for _, testCase := range testCases {
+		output := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output}
+		set := flag.NewFlagSet("test", 0)
+		args := append([]string{"releases", "--repository", repositoryPath, "--since", "2023-03-31", "--until", "2023-04-30", "--incidents", incidentsPath}, testCase.args...)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandReleases().Run(cCtx, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cliOutput ReleasesCliOutput
+		json.Unmarshal(output.Bytes(), &cliOutput)
+		for _, release := range cliOutput.Releases {
+			if release.Tag != "v2.0.0" {
+				continue
+			}
+			// incidents have no restoring release, so that time to restore is only in restorations
+			if release.Result.IsSuccess || release.Result.TimeToRestore != nil {
+				t.Errorf("%v: v2.0.0 should be failure without time to restore of its own but %+v", testCase.args, release.Result)
+			}
+			if len(release.Result.Restorations) != testCase.expectedRestorations {
+				t.Errorf("%v: v2.0.0 should have %v restorations but %+v", testCase.args, testCase.expectedRestorations, release.Result.Restorations)
+			}
+		}
+	}
+}
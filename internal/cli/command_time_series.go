@@ -12,14 +12,45 @@ import (
 type TimeSeriesCliOutput struct {
 	Option *core.Option          `json:"option"`
 	Items  []TimeSeriesDataPoint `json:"items"`
+	// Components are time series of each component in monorepo mode
+	Components []ComponentTimeSeriesCliOutput `json:"components,omitempty"`
+}
+
+type ComponentTimeSeriesCliOutput struct {
+	Component string                `json:"component"`
+	Items     []TimeSeriesDataPoint `json:"items"`
 }
 
 type TimeSeriesDataPoint struct {
-	Date                time.Time `json:"time"`
-	DeploymentFrequency float64   `json:"deploymentFrequency"`
-	LeadTimeForChanges  float64   `json:"leadTimeForChanges"`
-	TimeToRestore       float64   `json:"timeToRestore"`
-	ChangeFailureRate   float64   `json:"changeFailureRate"`
+	Date                     time.Time `json:"time"`
+	DeploymentFrequency      float64   `json:"deploymentFrequency"`
+	LeadTimeForChanges       float64   `json:"leadTimeForChanges"`
+	LeadTimeForChangesMedian float64   `json:"leadTimeForChangesMedian"`
+	LeadTimeForChangesP75    float64   `json:"leadTimeForChangesP75"`
+	LeadTimeForChangesP90    float64   `json:"leadTimeForChangesP90"`
+	LeadTimeForChangesP95    float64   `json:"leadTimeForChangesP95"`
+	TimeToRestore            float64   `json:"timeToRestore"`
+	TimeToRestoreCount       int       `json:"timeToRestoreCount"`
+	TimeToRestoreMedian      float64   `json:"timeToRestoreMedian"`
+	TimeToRestoreMax         float64   `json:"timeToRestoreMax"`
+	ChangeFailureRate        float64   `json:"changeFailureRate"`
+	ReworkRate               float64   `json:"reworkRate"`
+	// BatchSizeX are means and BatchSizeXMedian are medians of batch size of releases. They are present only if --batchSize
+	BatchSizeCommits            *float64 `json:"batchSizeCommits,omitempty"`
+	BatchSizeCommitsMedian      *float64 `json:"batchSizeCommitsMedian,omitempty"`
+	BatchSizeAuthors            *float64 `json:"batchSizeAuthors,omitempty"`
+	BatchSizeAuthorsMedian      *float64 `json:"batchSizeAuthorsMedian,omitempty"`
+	BatchSizeFilesChanged       *float64 `json:"batchSizeFilesChanged,omitempty"`
+	BatchSizeFilesChangedMedian *float64 `json:"batchSizeFilesChangedMedian,omitempty"`
+	BatchSizeLinesAdded         *float64 `json:"batchSizeLinesAdded,omitempty"`
+	BatchSizeLinesAddedMedian   *float64 `json:"batchSizeLinesAddedMedian,omitempty"`
+	BatchSizeLinesDeleted       *float64 `json:"batchSizeLinesDeleted,omitempty"`
+	BatchSizeLinesDeletedMedian *float64 `json:"batchSizeLinesDeletedMedian,omitempty"`
+	// Performance is classified by deployments per day of the interval
+	Performance core.Performance `json:"performance"`
+	// WorkingLeadTimeForChanges and WorkingTimeToRestore are means in working time by hour. They are present only if the working calendar is specified
+	WorkingLeadTimeForChanges *float64 `json:"workingLeadTimeForChanges,omitempty"`
+	WorkingTimeToRestore      *float64 `json:"workingTimeToRestore,omitempty"`
 }
 
 func GetCommandTimeSeries() *cli.Command {
@@ -30,7 +61,7 @@ func GetCommandTimeSeries() *cli.Command {
 		Action: func(ctx *cli.Context) error {
 			context := &CliContextWrapper{context: ctx}
 			context.Debugln("In debug mode")
-			releases, err := QueryReleases(context)
+			releases, components, err := QueryReleases(context)
 			if err != nil {
 				context.Error(err)
 				return err
@@ -44,14 +75,35 @@ func GetCommandTimeSeries() *cli.Command {
 			if err != nil {
 				return err
 			}
+			thresholds, err := context.PerformanceThresholds()
+			if err != nil {
+				return err
+			}
 
-			if !validateTimeSeriesInterval(timeSeriesOption.Interval, option.Since, option.Until) {
+			if timeSeriesOption.IsRolling() {
+				if option.Until.Sub(option.Since) < timeSeriesOption.Window {
+					err := fmt.Errorf("Window is longer than the time range")
+					return err
+				}
+			} else if !validateTimeSeriesInterval(timeSeriesOption.Interval, option.Since, option.Until) {
 				err := fmt.Errorf("Interval is too short")
 				return err
 			}
+			mapReleases := func(releases []*core.Release) []TimeSeriesDataPoint {
+				if timeSeriesOption.IsRolling() {
+					return mapReleasesToRollingTimeSeriesCliOutput(releases, timeSeriesOption.Window, timeSeriesOption.Step, option.Since, option.Until, option.LeadTimeMode, option.WorkingCalendar, option.CountsChangedLines, thresholds)
+				}
+				return mapReleasesToTimeSeriesCliOutput(releases, timeSeriesOption.Interval, timeSeriesOption.WeekStart, option.Since, option.Until, option.LeadTimeMode, option.WorkingCalendar, option.CountsChangedLines, thresholds)
+			}
 			output := &TimeSeriesCliOutput{
 				Option: option,
-				Items:  mapReleasesToTimeSeriesCliOutput(releases, timeSeriesOption.Interval, option.Since, option.Until),
+				Items:  mapReleases(releases),
+			}
+			for _, component := range components {
+				output.Components = append(output.Components, ComponentTimeSeriesCliOutput{
+					Component: component.Component,
+					Items:     mapReleases(component.Releases),
+				})
 			}
 			releasesJson, err := json.Marshal(output)
 			if err != nil {
@@ -80,41 +132,107 @@ func validateTimeSeriesInterval(interval TimeSeriesInterval, since time.Time, un
 		if duration < 28*24*time.Hour {
 			return false
 		}
+	case Quarter:
+		if duration < 3*28*24*time.Hour {
+			return false
+		}
+	case Year:
+		if duration < 12*28*24*time.Hour {
+			return false
+		}
+	default:
+		if days, ok := interval.fixedDays(); ok && duration < days {
+			return false
+		}
 	}
 	return true
 }
 
-func mapReleasesToTimeSeriesCliOutput(releases []*core.Release, interval TimeSeriesInterval, since time.Time, until time.Time) []TimeSeriesDataPoint {
+func mapReleasesToTimeSeriesCliOutput(releases []*core.Release, interval TimeSeriesInterval, weekStart time.Weekday, since time.Time, until time.Time, leadTimeMode core.LeadTimeMode, calendar *core.WorkingCalendar, withBatchSize bool, thresholds core.PerformanceThresholds) []TimeSeriesDataPoint {
 	var items []TimeSeriesDataPoint
 	dateOfStart := until
 	switch interval {
 	case Week:
-		dateOfStart = time.Date(until.Year(), until.Month(), until.Day()-int(until.Weekday()), 0, 0, 0, 0, until.Location())
+		daysFromWeekStart := (int(until.Weekday()) - int(weekStart) + 7) % 7
+		dateOfStart = time.Date(until.Year(), until.Month(), until.Day()-daysFromWeekStart, 0, 0, 0, 0, until.Location())
 	case Month:
 		dateOfStart = time.Date(until.Year(), until.Month(), 1, 0, 0, 0, 0, until.Location())
+	case Quarter:
+		dateOfStart = time.Date(until.Year(), until.Month()-(until.Month()-1)%3, 1, 0, 0, 0, 0, until.Location())
+	case Year:
+		dateOfStart = time.Date(until.Year(), 1, 1, 0, 0, 0, 0, until.Location())
+	default:
+		// the first interval of fixed days ends at the day of until
+		if days, ok := interval.fixedDays(); ok {
+			dateOfStart = time.Date(until.Year(), until.Month(), until.Day()+1-int(days/(24*time.Hour)), 0, 0, 0, 0, until.Location())
+		}
 	}
 	dateOfEnd := until
 	for ; dateOfStart.After(since) || dateOfStart.Equal(since); dateOfStart = getBeforeDate(dateOfStart, interval) {
-		items = append(items, mapReleasesToTimeSeriesDataPoint(releases, dateOfStart, dateOfEnd, interval))
+		items = append(items, mapReleasesToTimeSeriesDataPoint(releases, dateOfStart, dateOfEnd, interval, leadTimeMode, calendar, withBatchSize, thresholds))
 		dateOfEnd = dateOfStart
 	}
 	return items
 }
 
-func mapReleasesToTimeSeriesDataPoint(releases []*core.Release, dateOfStart time.Time, dateOfEnd time.Time, interval TimeSeriesInterval) TimeSeriesDataPoint {
+// mapReleasesToRollingTimeSeriesCliOutput returns items of trailing window at each step from until (first item is the newest).
+// The time of each item is the end of its window, and windows are in the range from since to until.
+// Deployment frequency of each item is deployments per day.
+func mapReleasesToRollingTimeSeriesCliOutput(releases []*core.Release, window time.Duration, step time.Duration, since time.Time, until time.Time, leadTimeMode core.LeadTimeMode, calendar *core.WorkingCalendar, withBatchSize bool, thresholds core.PerformanceThresholds) []TimeSeriesDataPoint {
+	var items []TimeSeriesDataPoint
+	for dateOfEnd := until; !dateOfEnd.Add(-window).Before(since); dateOfEnd = dateOfEnd.Add(-step) {
+		item := mapReleasesToTimeSeriesDataPoint(releases, dateOfEnd.Add(-window), dateOfEnd, Day, leadTimeMode, calendar, withBatchSize, thresholds)
+		item.Date = dateOfEnd
+		items = append(items, item)
+	}
+	return items
+}
+
+func mapReleasesToTimeSeriesDataPoint(releases []*core.Release, dateOfStart time.Time, dateOfEnd time.Time, interval TimeSeriesInterval, leadTimeMode core.LeadTimeMode, calendar *core.WorkingCalendar, withBatchSize bool, thresholds core.PerformanceThresholds) TimeSeriesDataPoint {
 	var releasesInInterval []*core.Release
 	for _, release := range releases {
 		if release.Date.After(dateOfStart) && release.Date.Before(dateOfEnd) {
 			releasesInInterval = append(releasesInInterval, release)
 		}
 	}
-	return TimeSeriesDataPoint{
-		Date:                dateOfStart,
-		DeploymentFrequency: core.GetDeploymentFrequencyByTimeunit(releasesInInterval, dateOfStart, dateOfEnd, string(interval)),
-		LeadTimeForChanges:  core.GetMeanLeadTimeForChanges(releasesInInterval).Hours(),
-		TimeToRestore:       core.GetTimeToRestore(releasesInInterval).Hours(),
-		ChangeFailureRate:   core.GetChangeFailureRate(releasesInInterval),
+	leadTimeForChangesStatistics := core.GetLeadTimeForChangesStatistics(releasesInInterval, leadTimeMode)
+	timeToRestoreStatistics := core.GetTimeToRestoreStatistics(releasesInInterval)
+	changeFailureRate := core.GetChangeFailureRate(releasesInInterval)
+	deploymentsPerDay := core.GetDeploymentFrequencyByTimeunit(releasesInInterval, dateOfStart, dateOfEnd, "day")
+	var workingLeadTimeForChanges, workingTimeToRestore *float64
+	if calendar != nil {
+		leadTime := core.GetWorkingLeadTimeForChangesStatistics(releasesInInterval, leadTimeMode, calendar).Mean.Hours()
+		timeToRestore := core.GetWorkingTimeToRestoreStatistics(releasesInInterval, calendar).Mean.Hours()
+		workingLeadTimeForChanges, workingTimeToRestore = &leadTime, &timeToRestore
+	}
+	item := TimeSeriesDataPoint{
+		Date:                      dateOfStart,
+		DeploymentFrequency:       core.GetDeploymentFrequencyByTimeunit(releasesInInterval, dateOfStart, dateOfEnd, string(interval)),
+		LeadTimeForChanges:        leadTimeForChangesStatistics.Mean.Hours(),
+		LeadTimeForChangesMedian:  leadTimeForChangesStatistics.Median.Hours(),
+		LeadTimeForChangesP75:     leadTimeForChangesStatistics.P75.Hours(),
+		LeadTimeForChangesP90:     leadTimeForChangesStatistics.P90.Hours(),
+		LeadTimeForChangesP95:     leadTimeForChangesStatistics.P95.Hours(),
+		TimeToRestore:             timeToRestoreStatistics.Mean.Hours(),
+		TimeToRestoreCount:        timeToRestoreStatistics.Count,
+		TimeToRestoreMedian:       timeToRestoreStatistics.Median.Hours(),
+		TimeToRestoreMax:          timeToRestoreStatistics.Max.Hours(),
+		ChangeFailureRate:         changeFailureRate,
+		ReworkRate:                core.GetReworkRate(releasesInInterval),
+		Performance:               classifyPerformance(releasesInInterval, deploymentsPerDay, leadTimeMode, leadTimeForChangesStatistics, timeToRestoreStatistics, changeFailureRate, thresholds),
+		WorkingLeadTimeForChanges: workingLeadTimeForChanges,
+		WorkingTimeToRestore:      workingTimeToRestore,
 	}
+	if withBatchSize {
+		statistics := core.GetBatchSizeStatistics(releasesInInterval)
+		mean, median := statistics.Mean, statistics.Median
+		item.BatchSizeCommits, item.BatchSizeCommitsMedian = &mean.Commits, &median.Commits
+		item.BatchSizeAuthors, item.BatchSizeAuthorsMedian = &mean.Authors, &median.Authors
+		item.BatchSizeFilesChanged, item.BatchSizeFilesChangedMedian = &mean.FilesChanged, &median.FilesChanged
+		item.BatchSizeLinesAdded, item.BatchSizeLinesAddedMedian = &mean.LinesAdded, &median.LinesAdded
+		item.BatchSizeLinesDeleted, item.BatchSizeLinesDeletedMedian = &mean.LinesDeleted, &median.LinesDeleted
+	}
+	return item
 }
 
 func getBeforeDate(date time.Time, interval TimeSeriesInterval) time.Time {
@@ -134,6 +252,13 @@ func getNextDate(date time.Time, interval TimeSeriesInterval, goBefore bool) tim
 		return date.AddDate(0, 0, 7*sign)
 	case Month:
 		return date.AddDate(0, 1*sign, 0)
+	case Quarter:
+		return date.AddDate(0, 3*sign, 0)
+	case Year:
+		return date.AddDate(1*sign, 0, 0)
+	}
+	if days, ok := interval.fixedDays(); ok {
+		return date.AddDate(0, 0, int(days/(24*time.Hour))*sign)
 	}
 	return date
 }
@@ -2,20 +2,67 @@ package cli
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/hmiyado/four-keys/internal/core"
 	"github.com/urfave/cli/v2"
 )
 
 type TimeSeriesInterval string
 
 const (
-	Day   TimeSeriesInterval = "day"
-	Week  TimeSeriesInterval = "week"
-	Month TimeSeriesInterval = "month"
+	Day     TimeSeriesInterval = "day"
+	Week    TimeSeriesInterval = "week"
+	Month   TimeSeriesInterval = "month"
+	Quarter TimeSeriesInterval = "quarter"
+	Year    TimeSeriesInterval = "year"
 )
 
+// fixedDays returns the duration of interval of fixed days such as "14d" and "2w".
+// It returns false for calendar intervals such as month, and for day and week that are aligned to calendar.
+func (i TimeSeriesInterval) fixedDays() (time.Duration, bool) {
+	if i == Day || i == Week {
+		return 0, false
+	}
+	return core.ParseFixedTimeunit(string(i))
+}
+
 type TimeSeriesOption struct {
 	Interval TimeSeriesInterval
+	// Window is the length of trailing window of each item. Zero means fixed buckets of Interval.
+	Window time.Duration
+	// Step is the distance between items of trailing windows
+	Step time.Duration
+	// WeekStart is the first day of each week of Week interval
+	WeekStart time.Weekday
+}
+
+// IsRolling returns true if items are trailing windows instead of buckets of Interval.
+func (o *TimeSeriesOption) IsRolling() bool {
+	return o.Window > 0
+}
+
+// parseDuration parses days or weeks (e.g. 30d, 2w) in addition to time.ParseDuration.
+func parseDuration(value string) (time.Duration, error) {
+	if duration, ok := core.ParseFixedTimeunit(value); ok {
+		return duration, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// positiveDuration returns the positive duration of flag name.
+// It returns zero if the flag is not set.
+func (c *CliContextWrapper) positiveDuration(name string) (time.Duration, error) {
+	value := c.context.String(name)
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := parseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("unavailable %s \"%s\". %s should be a positive duration such as 30d, 2w or 12h", name, value, name)
+	}
+	return duration, nil
 }
 
 func (c *CliContextWrapper) Interval() (*TimeSeriesInterval, error) {
@@ -24,13 +71,33 @@ func (c *CliContextWrapper) Interval() (*TimeSeriesInterval, error) {
 		interval := Month
 		return &interval, nil
 	}
-	validIntervals := []TimeSeriesInterval{Day, Week, Month}
+	validIntervals := []TimeSeriesInterval{Day, Week, Month, Quarter, Year}
 	for _, interval := range validIntervals {
 		if intervalString == string(interval) {
 			return &interval, nil
 		}
 	}
-	return nil, fmt.Errorf("unavailable interval \"%s\". Interval should be one of %s", intervalString, validIntervals)
+	interval := TimeSeriesInterval(intervalString)
+	if _, ok := interval.fixedDays(); ok {
+		return &interval, nil
+	}
+	return nil, fmt.Errorf("unavailable interval \"%s\". Interval should be one of %s or days such as 14d and 2w", intervalString, validIntervals)
+}
+
+// WeekStart returns the weekday of --weekStart. It defaults to Sunday.
+func (c *CliContextWrapper) WeekStart() (time.Weekday, error) {
+	weekStartString := c.context.String("weekStart")
+	if weekStartString == "" {
+		return time.Sunday, nil
+	}
+	validWeekdays := make([]string, 0, 7)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekStartString, weekday.String()) {
+			return weekday, nil
+		}
+		validWeekdays = append(validWeekdays, strings.ToLower(weekday.String()))
+	}
+	return time.Sunday, fmt.Errorf("unavailable weekStart \"%s\". weekStart should be one of %s", weekStartString, validWeekdays)
 }
 
 func (c *CliContextWrapper) TimeSeriesOption() (*TimeSeriesOption, error) {
@@ -38,8 +105,32 @@ func (c *CliContextWrapper) TimeSeriesOption() (*TimeSeriesOption, error) {
 	if error != nil {
 		return nil, error
 	}
+	window, error := c.positiveDuration("window")
+	if error != nil {
+		return nil, error
+	}
+	step, error := c.positiveDuration("step")
+	if error != nil {
+		return nil, error
+	}
+	if window > 0 && c.context.String("interval") != "" {
+		return nil, fmt.Errorf("window and interval cannot be used together")
+	}
+	if window == 0 && step > 0 {
+		return nil, fmt.Errorf("step requires window")
+	}
+	if step == 0 {
+		step = 24 * time.Hour
+	}
+	weekStart, error := c.WeekStart()
+	if error != nil {
+		return nil, error
+	}
 	return &TimeSeriesOption{
-		Interval: *interval,
+		Interval:  *interval,
+		Window:    window,
+		Step:      step,
+		WeekStart: weekStart,
 	}, nil
 }
 
@@ -47,8 +138,22 @@ func getCommandTimeSeriesFlags() []cli.Flag {
 	return []cli.Flag{
 		&cli.StringFlag{
 			Name:        "interval",
-			Usage:       "Interval of time series: day, week, month",
+			Usage:       "Interval of time series: day, week, month, quarter, year or days such as 14d and 2w",
 			DefaultText: "month",
 		},
+		&cli.StringFlag{
+			Name:  "window",
+			Usage: "Length of trailing window of each item instead of interval, e.g. 30d, 2w or 12h",
+		},
+		&cli.StringFlag{
+			Name:        "step",
+			Usage:       "Distance between items of trailing windows, e.g. 1d",
+			DefaultText: "1d",
+		},
+		&cli.StringFlag{
+			Name:        "weekStart",
+			Usage:       "First day of each week of week interval in --timezone, e.g. monday",
+			DefaultText: "sunday",
+		},
 	}
 }
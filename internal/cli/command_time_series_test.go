@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"flag"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/hmiyado/four-keys/internal/core"
+	"github.com/hmiyado/four-keys/internal/util"
 	"github.com/urfave/cli/v2"
 )
 
@@ -32,7 +35,7 @@ func TestGetCommandTimeSeriesShouldReturnTimeSeriesInDayly(t *testing.T) {
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-10-01",
 		"--until", "2022-10-31",
 		"--interval", "day"}
@@ -63,7 +66,7 @@ func TestGetCommandTimeSeriesShouldReturnErrorWhenDurationIsLessThanDay(t *testi
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-10-01",
 		"--until", "2022-10-01",
 		"--interval", "day"}
@@ -83,7 +86,7 @@ func TestGetCommandTimeSeriesShouldReturnTimeSeriesInWeekly(t *testing.T) {
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-10-01",
 		"--until", "2022-10-31",
 		"--interval", "week"}
@@ -111,7 +114,7 @@ func TestGetCommandTimeSeriesShouldReturnErrorWhenDurationIsLessThanWeek(t *test
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-10-01",
 		"--until", "2022-10-06",
 		"--interval", "week"}
@@ -131,7 +134,7 @@ func TestGetCommandTimeSeriesShouldReturnTimeSeriesInMonthly(t *testing.T) {
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-09-01",
 		"--until", "2022-10-31",
 		"--interval", "month"}
@@ -158,7 +161,7 @@ func TestGetCommandTimeSeriesShouldReturnErrorWhenDurationIsLessThanMonth(t *tes
 	set := flag.NewFlagSet("test", 0)
 	args := []string{
 		"timeSeries",
-		"--repository", "https://github.com/hmiyado/four-keys",
+		"--repository", repositoryPath,
 		"--since", "2022-10-01",
 		"--until", "2022-10-27",
 		"--interval", "month"}
@@ -171,3 +174,291 @@ func TestGetCommandTimeSeriesShouldReturnErrorWhenDurationIsLessThanMonth(t *tes
 		t.Errorf("timeSeries should return error when duration is less than day but stderr: %v stdout: %v", err, output.String())
 	}
 }
+
+func TestGetCommandTimeSeriesShouldReturnPerformanceOfEachItem(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--since", "2022-09-01",
+		"--until", "2022-10-31",
+		"--interval", "month"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	GetCommandTimeSeries().Run(cCtx, args...)
+
+	var cliOutput TimeSeriesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+
+	for _, item := range cliOutput.Items {
+		if item.Performance.Overall == "" {
+			t.Errorf("item of %v should have performance but %+v", item.Date, item.Performance)
+		}
+		if item.DeploymentFrequency == 0 && item.Performance.DeploymentFrequency != core.PerformanceTierLow {
+			t.Errorf("item of %v without deployment should be low but %v", item.Date, item.Performance.DeploymentFrequency)
+		}
+		if item.DeploymentFrequency == 0 && (item.Performance.LeadTimeForChanges != core.PerformanceTierUnknown || item.Performance.TimeToRestore != core.PerformanceTierUnknown || item.Performance.ChangeFailureRate != core.PerformanceTierUnknown) {
+			t.Errorf("item of %v without deployment should not classify other metrics but %+v", item.Date, item.Performance)
+		}
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnBatchSizeOnlyWithBatchSize(t *testing.T) {
+	for _, withBatchSize := range []bool{true, false} {
+		output := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output}
+		set := flag.NewFlagSet("test", 0)
+		args := []string{
+			"timeSeries",
+			"--repository", repositoryPath,
+			"--since", "2023-03-01",
+			"--until", "2023-05-31",
+			"--interval", "month"}
+		if withBatchSize {
+			args = append(args, "--batchSize")
+		}
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		GetCommandTimeSeries().Run(cCtx, args...)
+
+		var cliOutput TimeSeriesCliOutput
+		json.Unmarshal(output.Bytes(), &cliOutput)
+
+		if len(cliOutput.Items) == 0 {
+			t.Fatalf("timeSeries should have items but %v", output.String())
+		}
+		for _, item := range cliOutput.Items {
+			fields := []*float64{item.BatchSizeCommits, item.BatchSizeAuthors, item.BatchSizeFilesChanged, item.BatchSizeLinesAdded, item.BatchSizeLinesDeleted,
+				item.BatchSizeCommitsMedian, item.BatchSizeAuthorsMedian, item.BatchSizeFilesChangedMedian, item.BatchSizeLinesAddedMedian, item.BatchSizeLinesDeletedMedian}
+			if slices.ContainsFunc(fields, func(field *float64) bool { return (field != nil) != withBatchSize }) {
+				t.Errorf("item of %v should have batch size: %v but %+v", item.Date, withBatchSize, item)
+			}
+		}
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnRollingTimeSeries(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--since", "2023-03-01",
+		"--until", "2023-04-30",
+		"--window", "30d",
+		"--step", "1d"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandTimeSeries().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput TimeSeriesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+
+	if len(cliOutput.Items) != 31 {
+		t.Fatalf("timeSeries should have 31 items of 30 days window in 61 days but %v", len(cliOutput.Items))
+	}
+	first := cliOutput.Items[0]
+	if !first.Date.Equal(cliOutput.Option.Until) {
+		t.Errorf("first item should end at until %v but %v", cliOutput.Option.Until, first.Date)
+	}
+	if first.Date.Sub(cliOutput.Items[1].Date) != time.Hour*24 {
+		t.Errorf("items should be every step but %v", first.Date.Sub(cliOutput.Items[1].Date))
+	}
+	util.AssertIsNearBy(t, first.DeploymentFrequency, 0.1, 0.01)
+	util.AssertIsNearBy(t, first.ChangeFailureRate, 0.3333333333333333, 0.01)
+	last := cliOutput.Items[len(cliOutput.Items)-1]
+	if last.Date.Add(-30 * 24 * time.Hour).Before(cliOutput.Option.Since) {
+		t.Errorf("window of last item should start after since but %v", last.Date)
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnErrorOfInvalidWindow(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected string
+	}{
+		{[]string{"--window", "a month"}, "unavailable window"},
+		{[]string{"--window", "30d", "--step", "0d"}, "unavailable step"},
+		{[]string{"--window", "30d", "--interval", "day"}, "window and interval cannot be used together"},
+		{[]string{"--step", "1d"}, "step requires window"},
+		{[]string{"--window", "90d"}, "Window is longer than the time range"},
+	}
+	for _, testCase := range testCases {
+		output := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output}
+		set := flag.NewFlagSet("test", 0)
+		args := append([]string{
+			"timeSeries",
+			"--repository", repositoryPath,
+			"--since", "2023-03-01",
+			"--until", "2023-04-30"}, testCase.args...)
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandTimeSeries().Run(cCtx, args...)
+
+		if err == nil || !strings.Contains(err.Error(), testCase.expected) {
+			t.Errorf("timeSeries %v should return error of %v but %v", testCase.args, testCase.expected, err)
+		}
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnTimeSeriesInQuarterlyAndYearly(t *testing.T) {
+	testCases := []struct {
+		interval      string
+		expectedFirst time.Time
+		expectedCount int
+	}{
+		{"quarter", time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), 2},
+		{"year", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), 1},
+		{"14d", time.Date(2023, 4, 17, 0, 0, 0, 0, time.UTC), 8},
+	}
+	for _, testCase := range testCases {
+		output := bytes.NewBuffer([]byte{})
+		app := &cli.App{Writer: output}
+		set := flag.NewFlagSet("test", 0)
+		args := []string{
+			"timeSeries",
+			"--repository", repositoryPath,
+			"--since", "2023-01-01",
+			"--until", "2023-04-30",
+			"--interval", testCase.interval}
+		_ = set.Parse(args)
+
+		cCtx := cli.NewContext(app, set, nil)
+		err := GetCommandTimeSeries().Run(cCtx, args...)
+		if testCase.interval == "year" {
+			if err == nil || !strings.Contains(err.Error(), "Interval is too short") {
+				t.Errorf("timeSeries in year should return error when duration is less than year but %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cliOutput TimeSeriesCliOutput
+		json.Unmarshal(output.Bytes(), &cliOutput)
+		if len(cliOutput.Items) != testCase.expectedCount || !cliOutput.Items[0].Date.Equal(testCase.expectedFirst) {
+			t.Errorf("timeSeries in %v should have %v items from %v but %v", testCase.interval, testCase.expectedCount, testCase.expectedFirst, cliOutput.Items)
+		}
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnErrorOfInvalidInterval(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--interval", "fortnight"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandTimeSeries().Run(cCtx, args...)
+
+	if err == nil || !strings.Contains(err.Error(), "unavailable interval") {
+		t.Errorf("timeSeries should return error of invalid interval but %v", err)
+	}
+}
+
+func TestGetCommandTimeSeriesShouldAlignIntervalsToTimezone(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--since", "2023-04-01",
+		"--until", "2023-04-30",
+		"--interval", "week",
+		"--timezone", "Asia/Tokyo"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandTimeSeries().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput TimeSeriesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	expected := time.Date(2023, 4, 30, 0, 0, 0, 0, tokyo)
+	if !cliOutput.Items[0].Date.Equal(expected) {
+		t.Errorf("first week should start from %v but %v", expected, cliOutput.Items[0].Date)
+	}
+}
+
+func TestGetCommandTimeSeriesShouldStartWeeksFromWeekStart(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--since", "2023-04-01",
+		"--until", "2023-04-30",
+		"--interval", "week",
+		"--weekStart", "monday"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandTimeSeries().Run(cCtx, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cliOutput TimeSeriesCliOutput
+	json.Unmarshal(output.Bytes(), &cliOutput)
+
+	// 2023-04-30 is Sunday, so that the week starts from Monday 2023-04-24
+	expectedDates := []time.Time{
+		time.Date(2023, 4, 24, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 4, 17, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC),
+	}
+	if len(cliOutput.Items) != len(expectedDates) {
+		t.Fatalf("weeks should be %v but %v", expectedDates, cliOutput.Items)
+	}
+	for i, item := range cliOutput.Items {
+		if !item.Date.Equal(expectedDates[i]) {
+			t.Errorf("week %v should start from %v but %v", i, expectedDates[i], item.Date)
+		}
+	}
+	// v2.0.0 (04-15) is in the week from 04-10
+	if cliOutput.Items[2].DeploymentFrequency == 0 {
+		t.Errorf("week from 2023-04-10 should have v2.0.0 but %+v", cliOutput.Items[2])
+	}
+}
+
+func TestGetCommandTimeSeriesShouldReturnErrorOfInvalidWeekStart(t *testing.T) {
+	output := bytes.NewBuffer([]byte{})
+	app := &cli.App{Writer: output}
+	set := flag.NewFlagSet("test", 0)
+	args := []string{
+		"timeSeries",
+		"--repository", repositoryPath,
+		"--interval", "week",
+		"--weekStart", "mon"}
+	_ = set.Parse(args)
+
+	cCtx := cli.NewContext(app, set, nil)
+	err := GetCommandTimeSeries().Run(cCtx, args...)
+
+	if err == nil || !strings.Contains(err.Error(), "unavailable weekStart") {
+		t.Errorf("timeSeries should return error of invalid weekStart but %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// BatchSize is the size of changes shipped by a release compared with the previous release.
+type BatchSize struct {
+	Commits      int `json:"commits"`
+	Authors      int `json:"authors"`
+	FilesChanged int `json:"filesChanged"`
+	LinesAdded   int `json:"linesAdded"`
+	LinesDeleted int `json:"linesDeleted"`
+}
+
+// diffStat is the number of changed files and lines between two trees.
+type diffStat struct {
+	filesChanged int
+	linesAdded   int
+	linesDeleted int
+}
+
+// newBatchSize returns BatchSize of commits and diffStat of a release.
+func newBatchSize(commits []*Commit, stat diffStat) BatchSize {
+	return BatchSize{
+		Commits:      len(commits),
+		Authors:      countAuthors(commits),
+		FilesChanged: stat.filesChanged,
+		LinesAdded:   stat.linesAdded,
+		LinesDeleted: stat.linesDeleted,
+	}
+}
+
+// countAuthors returns the number of distinct authors by email, or by name if email is empty.
+func countAuthors(commits []*Commit) int {
+	authors := make(map[string]bool)
+	for _, commit := range commits {
+		author := strings.ToLower(commit.AuthorEmail)
+		if author == "" {
+			author = commit.AuthorName
+		}
+		authors[author] = true
+	}
+	return len(authors)
+}
+
+// BatchSizeStatistic is mean or median of each field of BatchSize.
+type BatchSizeStatistic struct {
+	Commits      float64 `json:"commits"`
+	Authors      float64 `json:"authors"`
+	FilesChanged float64 `json:"filesChanged"`
+	LinesAdded   float64 `json:"linesAdded"`
+	LinesDeleted float64 `json:"linesDeleted"`
+}
+
+type BatchSizeStatistics struct {
+	Mean   BatchSizeStatistic `json:"mean"`
+	Median BatchSizeStatistic `json:"median"`
+}
+
+// GetBatchSizeStatistics returns mean and median of BatchSize of releases.
+func GetBatchSizeStatistics(releases []*Release) BatchSizeStatistics {
+	fields := [5][]float64{}
+	for _, release := range releases {
+		size := release.BatchSize
+		for i, value := range []int{size.Commits, size.Authors, size.FilesChanged, size.LinesAdded, size.LinesDeleted} {
+			fields[i] = append(fields[i], float64(value))
+		}
+	}
+	statistic := func(aggregate func([]float64) float64) BatchSizeStatistic {
+		return BatchSizeStatistic{
+			Commits:      aggregate(fields[0]),
+			Authors:      aggregate(fields[1]),
+			FilesChanged: aggregate(fields[2]),
+			LinesAdded:   aggregate(fields[3]),
+			LinesDeleted: aggregate(fields[4]),
+		}
+	}
+	return BatchSizeStatistics{
+		Mean: statistic(getMean),
+		Median: statistic(func(values []float64) float64 {
+			return getPercentile(values, 50)
+		}),
+	}
+}
+
+// emptyTreeHash is the hash of the tree without files, which git knows without the object in the repository
+const emptyTreeHash = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// getDiffStatByLocalGit gets diffStat from the previous release of sources[i] by using local git command.
+// The first release is compared with the empty tree.
+// If paths are specified, only files that match the paths are counted.
+func getDiffStatByLocalGit(sources []ReleaseSource, i int, gitDirectory string, paths []string) (diffStat, error) {
+	args := []string{"diff-tree", "-r", "--numstat", "--no-renames"}
+	previousTree := emptyTreeHash
+	if i < len(sources)-1 {
+		previousTree = sources[i+1].commit.Hash.String()
+	}
+	args = append(args, previousTree, sources[i].commit.Hash.String())
+	if len(paths) > 0 {
+		args = append(args, "--")
+		for _, path := range paths {
+			args = append(args, ":(glob)"+path)
+		}
+	}
+	output, err := runLocalGit(gitDirectory, args...)
+	if err != nil {
+		return diffStat{}, err
+	}
+	stat := diffStat{}
+	// each line is "<added>\t<deleted>\t<path>", and added and deleted are "-" for binary files
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		stat.filesChanged++
+		if added, err := strconv.Atoi(fields[0]); err == nil {
+			stat.linesAdded += added
+		}
+		if deleted, err := strconv.Atoi(fields[1]); err == nil {
+			stat.linesDeleted += deleted
+		}
+	}
+	return stat, nil
+}
+
+// getDiffStatByGoGit gets diffStat from the previous release of sources[i] by using go-git.
+// The first release is compared with the empty tree.
+// If paths are specified, only files that match the paths are counted.
+func getDiffStatByGoGit(sources []ReleaseSource, i int, paths []string) (diffStat, error) {
+	tree, err := sources[i].commit.Tree()
+	if err != nil {
+		return diffStat{}, err
+	}
+	var previousTree *object.Tree
+	if i < len(sources)-1 {
+		if previousTree, err = sources[i+1].commit.Tree(); err != nil {
+			return diffStat{}, err
+		}
+	}
+	changes, err := object.DiffTree(previousTree, tree)
+	if err != nil {
+		return diffStat{}, err
+	}
+	if len(paths) > 0 {
+		filteredChanges := make(object.Changes, 0)
+		for _, change := range changes {
+			for _, name := range []string{change.From.Name, change.To.Name} {
+				if name != "" && matchesPathPatterns(paths, name) {
+					filteredChanges = append(filteredChanges, change)
+					break
+				}
+			}
+		}
+		changes = filteredChanges
+	}
+	stat := diffStat{}
+	for _, change := range changes {
+		added, deleted, err := countChangedLines(change)
+		if err != nil {
+			return diffStat{}, err
+		}
+		stat.filesChanged++
+		stat.linesAdded += added
+		stat.linesDeleted += deleted
+	}
+	return stat, nil
+}
+
+// countChangedLines returns the number of added and deleted lines of the patch of change as `git diff --numstat`.
+// Lines of binary files are not counted.
+func countChangedLines(change *object.Change) (int, int, error) {
+	patch, err := change.Patch()
+	if err != nil {
+		return 0, 0, err
+	}
+	added, deleted := 0, 0
+	for _, stat := range patch.Stats() {
+		added += stat.Addition
+		deleted += stat.Deletion
+	}
+	return added, deleted, nil
+}
@@ -0,0 +1,46 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// revertPattern matches the message that `git revert` generates
+var revertPattern = regexp.MustCompile(`This reverts commit ([0-9a-f]{7,40})`)
+
+// Commit is a commit shipped by a release.
+type Commit struct {
+	Hash          string    `json:"hash"`
+	Message       string    `json:"message"`
+	AuthorName    string    `json:"authorName"`
+	AuthorEmail   string    `json:"authorEmail"`
+	AuthorWhen    time.Time `json:"authorWhen"`
+	CommitterWhen time.Time `json:"committerWhen"`
+}
+
+// Subject returns the first line of the message.
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(c.Message, "\n")
+	return subject
+}
+
+// getOldestCommit returns the commit that has the oldest committer time. It returns nil if commits is empty.
+func getOldestCommit(commits []*Commit) *Commit {
+	var oldest *Commit
+	for _, commit := range commits {
+		if oldest == nil || commit.CommitterWhen.Before(oldest.CommitterWhen) {
+			oldest = commit
+		}
+	}
+	return oldest
+}
+
+// revertedHashes returns hashes of commits that this commit reverts.
+func (c *Commit) revertedHashes() []string {
+	hashes := make([]string, 0)
+	for _, match := range revertPattern.FindAllStringSubmatch(c.Message, -1) {
+		hashes = append(hashes, match[1])
+	}
+	return hashes
+}
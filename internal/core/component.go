@@ -0,0 +1,80 @@
+package core
+
+import (
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// ComponentReleases are releases of a component of monorepo.
+type ComponentReleases struct {
+	Component string
+	Releases  []*Release
+}
+
+// componentSources are release sources of a component
+type componentSources struct {
+	component string
+	sources   []ReleaseSource
+}
+
+// groupReleaseSourcesByComponent groups sources by component of option.ComponentPattern.
+// Sources whose name does not match the pattern are removed.
+// Groups are sorted by component, and the order of sources is kept in each group.
+func groupReleaseSourcesByComponent(sources []ReleaseSource, option *Option) []componentSources {
+	groups := make(map[string]*componentSources)
+	for _, source := range sources {
+		component, ok := option.componentOf(source.name)
+		if !ok {
+			option.Debugln(source.name, "is ignored for no component")
+			continue
+		}
+		if _, exists := groups[component]; !exists {
+			groups[component] = &componentSources{component: component, sources: make([]ReleaseSource, 0)}
+		}
+		groups[component].sources = append(groups[component].sources, source)
+	}
+	components := make([]componentSources, 0, len(groups))
+	for _, group := range groups {
+		components = append(components, *group)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].component < components[j].component
+	})
+	return components
+}
+
+// QueryComponentReleases returns releases of each component of option.ComponentPattern.
+// Releases of each component are independent, so that the previous release and failures are found in the same component.
+// It returns empty if option.ComponentPattern is nil.
+func QueryComponentReleases(repository *git.Repository, option *Option) []ComponentReleases {
+	components := make([]ComponentReleases, 0)
+	if option == nil || option.ComponentPattern == nil {
+		return components
+	}
+	option.StartTimer("QueryComponentReleases")
+	defer option.StopTimer("QueryComponentReleases")
+	sources := queryReleaseSources(repository, option)
+	sources = ignoreReleases(sources, option)
+	for _, group := range groupReleaseSourcesByComponent(sources, option) {
+		option.Debugln("Sources count of", group.component, ":", len(group.sources))
+		releases := queryReleasesOfSources(group.sources, option, repository)
+		for _, release := range releases {
+			release.Component = group.component
+		}
+		components = append(components, ComponentReleases{Component: group.component, Releases: releases})
+	}
+	return components
+}
+
+// AggregateComponentReleases returns releases of all components sorted by date (first item is the newest).
+func AggregateComponentReleases(components []ComponentReleases) []*Release {
+	releases := make([]*Release, 0)
+	for _, component := range components {
+		releases = append(releases, component.Releases...)
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		return releases[i].Date.After(releases[j].Date)
+	})
+	return releases
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+func TestQueryComponentReleasesShouldBeIndependentForEachComponent(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "p1" date: "2023-04-01" tag: "payments/v1.0.0"
+  commit id: "w1" date: "2023-04-02" tag: "web/v1.0.0"
+  commit id: "p2" date: "2023-04-10" tag: "payments/v1.1.0"
+  commit id: "w2" msg: "hotfix: web" date: "2023-04-12" tag: "web/v1.0.1"
+  commit id: "d1" date: "2023-04-13" tag: "docs"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	option := &Option{
+		Since:            time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:            time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		ComponentPattern: regexp.MustCompile(`^([^/]+)/`),
+		ReleaseOrder:     ReleaseOrderSemver,
+	}
+
+	components := QueryComponentReleases(r, option)
+
+	if len(components) != 2 || components[0].Component != "payments" || components[1].Component != "web" {
+		t.Fatalf("components should be payments and web but %v", components)
+	}
+	// payments/v1.1.0 ships w1 and p2 after payments/v1.0.0
+	assertReleasesAreEqual(t, []*Release{
+		{
+			Tag:                "payments/v1.1.0",
+			Date:               time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("192h"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+		{
+			Tag:                "payments/v1.0.0",
+			Date:               time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("0s"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+	}, components[0].Releases)
+	// the hotfix of web fixes web/v1.0.0 but not payments/v1.1.0
+	assertReleasesAreEqual(t, []*Release{
+		{
+			Tag:                "web/v1.0.1",
+			Date:               time.Date(2023, 4, 12, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("48h"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+		{
+			Tag:                "web/v1.0.0",
+			Date:               time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("24h"),
+			Result:             ReleaseResult{IsSuccess: false},
+		},
+	}, components[1].Releases)
+
+	releases := QueryReleases(r, option)
+	tags := make([]string, 0)
+	for _, release := range releases {
+		tags = append(tags, release.Component+":"+release.Tag)
+	}
+	expectedTags := []string{"web:web/v1.0.1", "payments:payments/v1.1.0", "web:web/v1.0.0", "payments:payments/v1.0.0"}
+	if len(tags) != len(expectedTags) {
+		t.Fatalf("releases should be %v but %v", expectedTags, tags)
+	}
+	for i := range tags {
+		if tags[i] != expectedTags[i] {
+			t.Errorf("releases should be %v but %v", expectedTags, tags)
+			break
+		}
+	}
+}
+
+func TestQueryComponentReleasesShouldBeEmptyWithoutComponentPattern(t *testing.T) {
+	components := QueryComponentReleases(repository, &Option{
+		Since: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+	})
+
+	if len(components) != 0 {
+		t.Errorf("components should be empty but %v", components)
+	}
+}
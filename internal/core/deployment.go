@@ -0,0 +1,183 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// DeploymentStatusSuccess is the status of successful deployment.
+// Deployments without status are also regarded as successful.
+const DeploymentStatusSuccess = "success"
+
+// Deployment is an event that a commit is deployed to an environment.
+type Deployment struct {
+	// Sha is the full or abbreviated hash of the deployed commit
+	Sha string `json:"sha"`
+	// Tag is the deployed tag. It takes precedence over Sha
+	Tag         string    `json:"tag"`
+	Timestamp   time.Time `json:"timestamp"`
+	Environment string    `json:"environment"`
+	Status      string    `json:"status"`
+}
+
+func (d Deployment) ref() string {
+	if d.Tag != "" {
+		return d.Tag
+	}
+	return d.Sha
+}
+
+func (d Deployment) isSuccess() bool {
+	return d.Status == "" || d.Status == DeploymentStatusSuccess
+}
+
+// ReadDeployments reads deployment event log from path.
+// The file is CSV if its extension is ".csv", otherwise JSON Lines.
+// CSV must have header with columns of sha or tag, timestamp, environment and status.
+// Timestamp is in RFC3339 format.
+func ReadDeployments(path string) ([]Deployment, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return parseDeploymentsCsv(file)
+	}
+	return parseDeploymentsJsonLines(file)
+}
+
+func parseDeploymentsJsonLines(reader io.Reader) ([]Deployment, error) {
+	deployments := make([]Deployment, 0)
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var deployment Deployment
+		if err := json.Unmarshal([]byte(line), &deployment); err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+		if deployment.ref() == "" {
+			return nil, fmt.Errorf("line %v: sha or tag is required", lineNumber)
+		}
+		deployments = append(deployments, deployment)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return deployments, nil
+}
+
+func parseDeploymentsCsv(reader io.Reader) ([]Deployment, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	deployments := make([]Deployment, 0)
+	if len(records) == 0 {
+		return deployments, nil
+	}
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		columns[strings.TrimSpace(column)] = i
+	}
+	if _, ok := columns["timestamp"]; !ok {
+		return nil, fmt.Errorf("header: timestamp column is required")
+	}
+	valueOf := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	for i, record := range records[1:] {
+		lineNumber := i + 2
+		timestamp, err := time.Parse(time.RFC3339, valueOf(record, "timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", lineNumber, err)
+		}
+		deployment := Deployment{
+			Sha:         valueOf(record, "sha"),
+			Tag:         valueOf(record, "tag"),
+			Timestamp:   timestamp,
+			Environment: valueOf(record, "environment"),
+			Status:      valueOf(record, "status"),
+		}
+		if deployment.ref() == "" {
+			return nil, fmt.Errorf("line %v: sha or tag is required", lineNumber)
+		}
+		deployments = append(deployments, deployment)
+	}
+	return deployments, nil
+}
+
+// DeploymentEnvironments returns distinct environments of deployments in order of appearance.
+func DeploymentEnvironments(deployments []Deployment) []string {
+	environments := make([]string, 0)
+	for _, deployment := range deployments {
+		if !slices.Contains(environments, deployment.Environment) {
+			environments = append(environments, deployment.Environment)
+		}
+	}
+	return environments
+}
+
+// getReleaseSourcesFromDeployments returns ReleaseSource of each successful deployment of option.Deployments.
+// Date of each source is the time of deployment.
+// Deployments to other than option.DeploymentEnvironment and deployments whose commit is not found are skipped.
+// Only the first deployment of each commit to each environment is a release,
+// so that a redeployment or a rollback does not ship the same commits again.
+func getReleaseSourcesFromDeployments(repository *git.Repository, option *Option) []ReleaseSource {
+	deployments := slices.Clone(option.Deployments)
+	slices.SortStableFunc(deployments, func(a, b Deployment) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	deployedCommits := make(map[string]bool)
+	sources := make([]ReleaseSource, 0)
+	for _, deployment := range deployments {
+		if option.DeploymentEnvironment != "" && deployment.Environment != option.DeploymentEnvironment {
+			continue
+		}
+		if !deployment.isSuccess() {
+			option.Debugln("deployment of", deployment.ref(), "is skipped for status", deployment.Status)
+			continue
+		}
+		hash, err := repository.ResolveRevision(plumbing.Revision(deployment.ref()))
+		if err != nil {
+			option.Debugln("deployment of", deployment.ref(), "is skipped:", err)
+			continue
+		}
+		commit, err := repository.CommitObject(*hash)
+		if err != nil {
+			option.Debugln("deployment of", deployment.ref(), "is skipped:", err)
+			continue
+		}
+		deployedCommit := deployment.Environment + "\x00" + commit.Hash.String()
+		if deployedCommits[deployedCommit] {
+			option.Debugln("deployment of", deployment.ref(), "is skipped for redeployment to", deployment.Environment)
+			continue
+		}
+		deployedCommits[deployedCommit] = true
+		name := deployment.Tag
+		if name == "" {
+			name = commit.Hash.String()[:7]
+		}
+		sources = append(sources, ReleaseSource{name: name, commit: commit, date: deployment.Timestamp})
+	}
+	sortReleaseSources(sources)
+	return sources
+}
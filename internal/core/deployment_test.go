@@ -0,0 +1,159 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDeploymentsCsvShouldReadColumnsByHeader(t *testing.T) {
+	deployments, err := parseDeploymentsCsv(strings.NewReader(`environment,timestamp,sha,tag,status
+production,2023-04-02T09:00:00+09:00,0123abc,,success
+staging,2023-04-01T00:00:00Z,,v1.0.0,failure
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Deployment{
+		{Sha: "0123abc", Timestamp: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), Environment: "production", Status: "success"},
+		{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), Environment: "staging", Status: "failure"},
+	}
+	assertDeploymentsAreEqual(t, expected, deployments)
+}
+
+func TestParseDeploymentsCsvShouldFailWithoutRef(t *testing.T) {
+	_, err := parseDeploymentsCsv(strings.NewReader(`timestamp,environment
+2023-04-02T00:00:00Z,production
+`))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("deployment without sha or tag should be error of line 2 but %v", err)
+	}
+}
+
+func TestParseDeploymentsJsonLinesShouldReadEachLine(t *testing.T) {
+	deployments, err := parseDeploymentsJsonLines(strings.NewReader(`{"sha":"0123abc","timestamp":"2023-04-02T00:00:00Z","environment":"production","status":"success"}
+
+{"tag":"v1.0.0","timestamp":"2023-04-01T00:00:00Z"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Deployment{
+		{Sha: "0123abc", Timestamp: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), Environment: "production", Status: "success"},
+		{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	assertDeploymentsAreEqual(t, expected, deployments)
+}
+
+func TestQueryReleasesShouldUseDeploymentTimeAsReleaseDate(t *testing.T) {
+	tag2_0_1, _ := repository.Tag("v2.0.1")
+	sha2_0_1 := tag2_0_1.Hash().String()[:10]
+	releases := QueryReleases(repository, &Option{
+		Since:         time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+		ReleaseSource: ReleaseSourceDeployment,
+		Deployments: []Deployment{
+			{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), Environment: "production"},
+			{Tag: "v2.0.0", Timestamp: time.Date(2023, 4, 16, 12, 0, 0, 0, time.UTC), Environment: "production", Status: "success"},
+			{Tag: "v2.0.0", Timestamp: time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC), Environment: "staging", Status: "success"},
+			{Sha: sha2_0_1, Timestamp: time.Date(2023, 4, 30, 12, 0, 0, 0, time.UTC), Environment: "production", Status: "failure"},
+			{Sha: sha2_0_1, Timestamp: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), Environment: "production", Status: "success"},
+			{Tag: "unknown", Timestamp: time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), Environment: "production"},
+		},
+		DeploymentEnvironment: "production",
+	})
+
+	expected := []*Release{
+		{
+			Tag:                tag2_0_1.Hash().String()[:7],
+			Date:               time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("144h"),
+			Result:             ReleaseResult{IsSuccess: true, TimeToRestore: parseDurationOrNil("348h")},
+		},
+		{
+			Tag:                "v2.0.0",
+			Date:               time.Date(2023, 4, 16, 12, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("276h"),
+			Result:             ReleaseResult{IsSuccess: false},
+		},
+		{
+			Tag:                "v1.0.0",
+			Date:               time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("768h"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+	}
+	assertReleasesAreEqual(t, expected, releases)
+}
+
+func TestQueryReleasesShouldNotCountRedeploymentAsRelease(t *testing.T) {
+	testCases := []struct {
+		name        string
+		deployments []Deployment
+		expected    []*Release
+	}{
+		{
+			name: "redeployment",
+			deployments: []Deployment{
+				{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC)},
+				{Tag: "v2.0.0", Timestamp: time.Date(2023, 4, 17, 0, 0, 0, 0, time.UTC)},
+				{Tag: "v2.0.0", Timestamp: time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC)},
+			},
+			expected: []*Release{
+				{Tag: "v2.0.0", Date: time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("264h"), Result: ReleaseResult{IsSuccess: true}},
+				{Tag: "v1.0.0", Date: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("768h"), Result: ReleaseResult{IsSuccess: true}},
+			},
+		},
+		{
+			// the next release after the rollback ships only commits from v2.0.0
+			name: "rollback",
+			deployments: []Deployment{
+				{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC)},
+				{Tag: "v2.0.0", Timestamp: time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC)},
+				{Tag: "v1.0.0", Timestamp: time.Date(2023, 4, 17, 0, 0, 0, 0, time.UTC)},
+				{Tag: "v2.0.1", Timestamp: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC)},
+			},
+			expected: []*Release{
+				{Tag: "v2.0.1", Date: time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("144h"), Result: ReleaseResult{IsSuccess: true, TimeToRestore: parseDurationOrNil("360h")}},
+				{Tag: "v2.0.0", Date: time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("264h"), Result: ReleaseResult{IsSuccess: false}},
+				{Tag: "v1.0.0", Date: time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("768h"), Result: ReleaseResult{IsSuccess: true}},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			releases := QueryReleases(repository, &Option{
+				Since:         time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+				Until:         time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+				ReleaseSource: ReleaseSourceDeployment,
+				Deployments:   testCase.deployments,
+			})
+			assertReleasesAreEqual(t, testCase.expected, releases)
+		})
+	}
+}
+
+func TestDeploymentEnvironmentsShouldReturnDistinctEnvironments(t *testing.T) {
+	environments := DeploymentEnvironments([]Deployment{{Environment: "staging"}, {Environment: "production"}, {Environment: "staging"}})
+	if strings.Join(environments, ",") != "staging,production" {
+		t.Errorf("environments should be staging and production but %v", environments)
+	}
+}
+
+func assertDeploymentsAreEqual(t *testing.T, expected []Deployment, actual []Deployment) {
+	t.Helper()
+	if len(expected) != len(actual) {
+		t.Fatalf("deployments should be %v but %v", expected, actual)
+	}
+	for i := range expected {
+		if expected[i].Sha != actual[i].Sha ||
+			expected[i].Tag != actual[i].Tag ||
+			!expected[i].Timestamp.Equal(actual[i].Timestamp) ||
+			expected[i].Environment != actual[i].Environment ||
+			expected[i].Status != actual[i].Status {
+			t.Errorf("deployments[%v] should be %v but %v", i, expected[i], actual[i])
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FailureDetector detects whether a release is failure and which release restores it.
+type FailureDetector interface {
+	// DetectFailure inspects releases[index].
+	// releases are in the order of Option.ReleaseOrder (newest first), so releases[index-1] is the next release and releases[index+1] is the previous release.
+	// With ReleaseOrderSemver, the next release can be dated before releases[index] (e.g. backport of older version),
+	// and such restoringRelease is ignored by the caller.
+	// restoringRelease is nil if the failure is not restored yet or time to restore is unknown.
+	DetectFailure(releases []*Release, index int) (isFailure bool, restoringRelease *Release)
+	// IsRework returns true if the release is an unplanned fix, e.g. it ships a fix commit.
+	IsRework(release *Release) bool
+}
+
+// fixCommitDetector is a FailureDetector that finds fix commits, in contrast to TagSuffixDetector.
+type fixCommitDetector interface {
+	isFixCommit(commit *Commit) bool
+}
+
+// hasFixCommit returns true if the release has a fix commit of detector.
+func hasFixCommit(release *Release, detector fixCommitDetector) bool {
+	for _, commit := range release.commits {
+		if detector.isFixCommit(commit) {
+			return true
+		}
+	}
+	return false
+}
+
+// FixCommitDetector regards a release as failure if the next release has a commit whose message matches Pattern.
+// Commit message with "hotfix" is regarded as fix commit if Pattern is nil.
+type FixCommitDetector struct {
+	Pattern *regexp.Regexp
+}
+
+func (d FixCommitDetector) DetectFailure(releases []*Release, index int) (bool, *Release) {
+	return detectFailureByFixRelease(releases, index, d.IsRework)
+}
+
+func (d FixCommitDetector) IsRework(release *Release) bool {
+	return hasFixCommit(release, d)
+}
+
+func (d FixCommitDetector) isFixCommit(commit *Commit) bool {
+	if d.Pattern == nil {
+		return strings.Contains(commit.Message, "hotfix")
+	}
+	return d.Pattern.MatchString(commit.Message)
+}
+
+// conventionalFixPattern matches the subject of a conventional commit of fix type, e.g. "fix(api)!: message"
+var conventionalFixPattern = regexp.MustCompile(`^fix(\([^)]*\))?!?: `)
+
+// ConventionalFixDetector regards a release as failure if the next release has a conventional commit of fix type.
+type ConventionalFixDetector struct{}
+
+func (d ConventionalFixDetector) DetectFailure(releases []*Release, index int) (bool, *Release) {
+	return detectFailureByFixRelease(releases, index, d.IsRework)
+}
+
+func (d ConventionalFixDetector) IsRework(release *Release) bool {
+	return hasFixCommit(release, d)
+}
+
+func (d ConventionalFixDetector) isFixCommit(commit *Commit) bool {
+	return conventionalFixPattern.MatchString(commit.Message)
+}
+
+// TagSuffixDetector regards a release as failure if the tag of the next release ends with Suffix, e.g. "-hotfix".
+type TagSuffixDetector struct {
+	Suffix string
+}
+
+func (d TagSuffixDetector) DetectFailure(releases []*Release, index int) (bool, *Release) {
+	return detectFailureByFixRelease(releases, index, d.IsRework)
+}
+
+func (d TagSuffixDetector) IsRework(release *Release) bool {
+	return d.Suffix != "" && strings.HasSuffix(release.Tag, d.Suffix)
+}
+
+// RevertCommitDetector regards a release as failure if any of its commits is reverted by a later release.
+// The oldest release that shipped the revert commit restores the failed release.
+type RevertCommitDetector struct{}
+
+func (d RevertCommitDetector) DetectFailure(releases []*Release, index int) (bool, *Release) {
+	release := releases[index]
+	for i := index - 1; i >= 0; i-- {
+		for _, commit := range releases[i].commits {
+			for _, revertedHash := range commit.revertedHashes() {
+				if release.hasCommit(revertedHash) {
+					return true, releases[i]
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// IsRework returns true if the release ships a revert commit.
+func (d RevertCommitDetector) IsRework(release *Release) bool {
+	return hasFixCommit(release, d)
+}
+
+func (d RevertCommitDetector) isFixCommit(commit *Commit) bool {
+	return len(commit.revertedHashes()) > 0
+}
+
+// detectFailureByFixRelease regards a release as failure if the next release is a fix release.
+// Failures in a row are restored once by the next successful release, and time to restore is counted from the oldest of them.
+// Time to restore is unknown if the failures start from the oldest release because the failure may start before it.
+func detectFailureByFixRelease(releases []*Release, index int, isFixRelease func(*Release) bool) (bool, *Release) {
+	isFailure := func(i int) bool {
+		return i > 0 && isFixRelease(releases[i-1])
+	}
+	if !isFailure(index) {
+		return false, nil
+	}
+	if index == len(releases)-1 || isFailure(index+1) {
+		return true, nil
+	}
+	restoring := index - 1
+	for isFailure(restoring) {
+		restoring--
+	}
+	return true, releases[restoring]
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+// createReleasesForFailureDetector returns releases of tags (newest first) released every 10 days.
+// Each release has a commit of messages whose hash is filled with the index of the release.
+func createReleasesForFailureDetector(tags []string, messages []string) []*Release {
+	releases := make([]*Release, 0)
+	for i, tag := range tags {
+		releases = append(releases, &Release{
+			Tag:     tag,
+			Date:    time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, 10*(len(tags)-1-i)),
+			commits: []*Commit{{Hash: strings.Repeat(fmt.Sprint(i), 40), Message: messages[i]}},
+		})
+	}
+	return releases
+}
+
+func assertFailureDetection(t *testing.T, detector FailureDetector, releases []*Release, index int, expectedFailure bool, expectedRestoring *Release) {
+	isFailure, restoring := detector.DetectFailure(releases, index)
+	if isFailure != expectedFailure || restoring != expectedRestoring {
+		t.Errorf("%v should be (failure=%v, restoring=%v) but (failure=%v, restoring=%v)", releases[index].Tag, expectedFailure, expectedRestoring, isFailure, restoring)
+	}
+}
+
+func TestFixCommitDetectorShouldRestoreFailuresInARowOnce(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v4", "v3", "v2", "v1"},
+		[]string{"hotfix: again", "hotfix: v2", "feature", "initial"},
+	)
+	detector := FixCommitDetector{}
+
+	assertFailureDetection(t, detector, releases, 0, false, nil)
+	assertFailureDetection(t, detector, releases, 1, true, nil)
+	assertFailureDetection(t, detector, releases, 2, true, releases[0])
+	assertFailureDetection(t, detector, releases, 3, false, nil)
+}
+
+func TestFixCommitDetectorShouldUsePattern(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v2", "v1"},
+		[]string{"[urgent] fix", "hotfix"},
+	)
+
+	assertFailureDetection(t, FixCommitDetector{Pattern: regexp.MustCompile(`^\[urgent\]`)}, releases, 1, true, nil)
+	assertFailureDetection(t, FixCommitDetector{}, releases, 1, false, nil)
+}
+
+func TestConventionalFixDetectorShouldDetectFixType(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v4", "v3", "v2", "v1"},
+		[]string{"fix(api)!: broken response", "feat: prefix of fix: is not fix", "fix: typo", "initial"},
+	)
+	detector := ConventionalFixDetector{}
+
+	assertFailureDetection(t, detector, releases, 1, true, releases[0])
+	assertFailureDetection(t, detector, releases, 2, false, nil)
+	assertFailureDetection(t, detector, releases, 3, true, nil)
+}
+
+func TestTagSuffixDetectorShouldDetectFixRelease(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v3", "v2-hotfix", "v2", "v1"},
+		[]string{"feature", "fix", "feature", "initial"},
+	)
+	detector := TagSuffixDetector{Suffix: "-hotfix"}
+
+	assertFailureDetection(t, detector, releases, 0, false, nil)
+	assertFailureDetection(t, detector, releases, 1, false, nil)
+	assertFailureDetection(t, detector, releases, 2, true, releases[1])
+	assertFailureDetection(t, TagSuffixDetector{}, releases, 2, false, nil)
+}
+
+func TestRevertCommitDetectorShouldBeRestoredByOldestRevert(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v4", "v3", "v2", "v1"},
+		[]string{"This reverts commit 2222222222222222222222222222222222222222.", "This reverts commit 2222222.", "feature", "initial"},
+	)
+	detector := RevertCommitDetector{}
+
+	assertFailureDetection(t, detector, releases, 2, true, releases[1])
+	assertFailureDetection(t, detector, releases, 3, false, nil)
+}
+
+func TestSetReleaseResultForEachReleaseShouldComposeFailureDetectors(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v4", "v3", "v2", "v1"},
+		[]string{"fix: v3", "feature", "hotfix: v1", "initial"},
+	)
+	setReleaseResultForEachRelease(releases, &Option{
+		Since:            time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:            time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+		FailureDetectors: []FailureDetector{FixCommitDetector{}, ConventionalFixDetector{}},
+	})
+
+	if !releases[0].Result.IsSuccess || releases[1].Result.IsSuccess || !releases[2].Result.IsSuccess || releases[3].Result.IsSuccess {
+		t.Errorf("v3 and v1 should be failure but %v", releases)
+	}
+	restorations := GetRestorations(releases)
+	if len(restorations) != 1 || restorations[0].FailedRelease != releases[1] || restorations[0].RestoringRelease != releases[0] {
+		t.Errorf("v4 should restore v3 but %v", restorations)
+	}
+	if !releases[0].IsRework || releases[1].IsRework || !releases[2].IsRework || releases[3].IsRework {
+		t.Errorf("v4 and v2 should be rework but %v", releases)
+	}
+	util.AssertIsNearBy(t, GetReworkRate(releases), 0.5, 0.01)
+}
+
+func TestFailureDetectorsShouldRegardFixReleaseAsRework(t *testing.T) {
+	releases := createReleasesForFailureDetector(
+		[]string{"v4-hotfix", "v3", "v2", "v1"},
+		[]string{"feature", "This reverts commit 3333333.", "fix: typo", "hotfix"},
+	)
+	detectors := []FailureDetector{FixCommitDetector{}, ConventionalFixDetector{}, RevertCommitDetector{}, TagSuffixDetector{Suffix: "-hotfix"}}
+	expected := map[FailureDetector]int{detectors[0]: 3, detectors[1]: 2, detectors[2]: 1, detectors[3]: 0}
+
+	for _, detector := range detectors {
+		for i, release := range releases {
+			if detector.IsRework(release) != (i == expected[detector]) {
+				t.Errorf("%T should regard only %v as rework but %v is %v", detector, releases[expected[detector]].Tag, release.Tag, detector.IsRework(release))
+			}
+		}
+	}
+}
@@ -1,39 +1,73 @@
 package core
 
-import "time"
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
 
 func GetDeploymentFrequency(releases []*Release, option Option) float64 {
 	return GetDeploymentFrequencyByTimeunit(releases, option.Since, option.Until, "day")
 }
 
 // GetDeploymentFrequencyByTimeunit returns deployment frequency by timeunit.
-// timeunitKind is one of "day", "week", "month". Default is "day".
+// timeunitKind is one of "day", "week", "month", "quarter", "year" or fixed days such as "14d" and "2w".
 // If timeunitKind is not one of them, "day" is used.
 func GetDeploymentFrequencyByTimeunit(releases []*Release, since time.Time, until time.Time, timeunitKind string) float64 {
 	duration := until.Sub(since)
 	frequency := float64(duration / (time.Hour * 24))
 	switch timeunitKind {
-	case "day":
-		frequency = float64(duration / (time.Hour * 24))
-		if frequency == 0 {
-			frequency = 1
-		}
-	case "week":
-		frequency = float64(duration / (time.Hour * 24 * 7))
-		if frequency == 0 {
-			frequency = 1
-		}
 	case "month":
 		frequency = float64((until.Year()-since.Year())*12 + int(until.Month()-since.Month()))
-		if frequency == 0 {
-			frequency = 1
+	case "quarter":
+		frequency = float64((until.Year()-since.Year())*4 + quarterOf(until) - quarterOf(since))
+	case "year":
+		frequency = float64(until.Year() - since.Year())
+	default:
+		if timeunit, ok := ParseFixedTimeunit(timeunitKind); ok {
+			frequency = float64(duration / timeunit)
 		}
 	}
+	if frequency == 0 {
+		frequency = 1
+	}
 
 	releasesCount := len(releases)
 	return float64(releasesCount) / frequency
 }
 
+// fixedTimeunitPattern matches timeunit of fixed days, e.g. "14d" or "2w"
+var fixedTimeunitPattern = regexp.MustCompile(`^([1-9][0-9]*)([dw])$`)
+
+// ParseFixedTimeunit returns the duration of timeunit of fixed days: "day", "week", "Nd" or "Nw".
+// It returns false if timeunit is not fixed days, e.g. "month".
+func ParseFixedTimeunit(timeunit string) (time.Duration, bool) {
+	day := 24 * time.Hour
+	switch timeunit {
+	case "day":
+		return day, true
+	case "week":
+		return 7 * day, true
+	}
+	matches := fixedTimeunitPattern.FindStringSubmatch(timeunit)
+	if matches == nil {
+		return 0, false
+	}
+	count, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	if matches[2] == "w" {
+		return time.Duration(count) * 7 * day, true
+	}
+	return time.Duration(count) * day, true
+}
+
+// quarterOf returns the quarter of date (0 to 3).
+func quarterOf(date time.Time) int {
+	return int(date.Month()-1) / 3
+}
+
 func GetMeanLeadTimeForChanges(releases []*Release) time.Duration {
 	if len(releases) == 0 {
 		return time.Duration(0)
@@ -45,18 +79,135 @@ func GetMeanLeadTimeForChanges(releases []*Release) time.Duration {
 	return time.Duration(int64(sum) / int64(len(releases)))
 }
 
-func GetTimeToRestore(releases []*Release) time.Duration {
-	sum := time.Duration(0)
-	countOfRestore := 0
+type LeadTimeForChangesStatistics struct {
+	Mean   time.Duration
+	Median time.Duration
+	P75    time.Duration
+	P90    time.Duration
+	P95    time.Duration
+}
+
+// GetLeadTimesForChanges returns samples of lead time for changes.
+// With LeadTimeModePerCommit, it returns lead time of each commit shipped by releases.
+// Otherwise, it returns LeadTimeForChanges of each release.
+// Excluded lead times and outliers are treated as the releases are queried.
+func GetLeadTimesForChanges(releases []*Release, mode LeadTimeMode) []time.Duration {
+	leadTimes := make([]time.Duration, 0)
 	for _, release := range releases {
-		if release.Result.TimeToRestore != nil {
-			sum += *release.Result.TimeToRestore
+		if release.isLeadTimeExcluded {
+			continue
+		}
+		for _, leadTime := range release.LeadTimesForChanges(mode) {
+			if treated, ok := release.outliers.treatLeadTime(leadTime); ok {
+				leadTimes = append(leadTimes, treated)
+			}
 		}
 	}
-	if countOfRestore == 0 {
-		return sum
+	return leadTimes
+}
+
+// GetLeadTimeForChangesStatistics returns mean and percentiles of lead time for changes measured by mode.
+func GetLeadTimeForChangesStatistics(releases []*Release, mode LeadTimeMode) LeadTimeForChangesStatistics {
+	return NewLeadTimeForChangesStatistics(GetLeadTimesForChanges(releases, mode))
+}
+
+// GetWorkingLeadTimeForChangesStatistics returns mean and percentiles of lead time for changes measured by mode,
+// counting only working time of calendar.
+// Lead times excluded or dropped as outliers in wall-clock time are also excluded, but working time is not winsorized.
+func GetWorkingLeadTimeForChangesStatistics(releases []*Release, mode LeadTimeMode, calendar *WorkingCalendar) LeadTimeForChangesStatistics {
+	leadTimes := make([]time.Duration, 0)
+	for _, release := range releases {
+		if release.isLeadTimeExcluded {
+			continue
+		}
+		wallClockLeadTimes := release.LeadTimesForChanges(mode)
+		for i, leadTime := range release.WorkingLeadTimesForChanges(mode, calendar) {
+			if _, ok := release.outliers.treatLeadTime(wallClockLeadTimes[i]); ok {
+				leadTimes = append(leadTimes, leadTime)
+			}
+		}
+	}
+	return NewLeadTimeForChangesStatistics(leadTimes)
+}
+
+// NewLeadTimeForChangesStatistics returns mean and percentiles of leadTimes.
+func NewLeadTimeForChangesStatistics(leadTimes []time.Duration) LeadTimeForChangesStatistics {
+	return LeadTimeForChangesStatistics{
+		Mean:   getMeanDuration(leadTimes),
+		Median: getPercentileDuration(leadTimes, 50),
+		P75:    getPercentileDuration(leadTimes, 75),
+		P90:    getPercentileDuration(leadTimes, 90),
+		P95:    getPercentileDuration(leadTimes, 95),
 	}
-	return sum / time.Duration(countOfRestore)
+}
+
+// Restoration is a pair of a failed release and the release that restored it.
+// RestoringRelease is nil if the failure is restored by an incident resolution.
+type Restoration struct {
+	FailedRelease    *Release
+	RestoringRelease *Release
+	TimeToRestore    time.Duration
+	// Start is when the failure started, and End is when it was restored
+	Start time.Time
+	End   time.Time
+}
+
+type TimeToRestoreStatistics struct {
+	Count        int
+	Mean         time.Duration
+	Median       time.Duration
+	Max          time.Duration
+	Restorations []Restoration
+}
+
+// GetRestorations returns restorations of failed releases in the order of releases.
+// A restoration is attributed to the restoring release, or to the failed release if it is restored by an incident resolution.
+// Outliers of time to restore are treated as the releases are queried.
+func GetRestorations(releases []*Release) []Restoration {
+	restorations := make([]Restoration, 0)
+	for _, release := range releases {
+		for _, restoration := range release.restorations {
+			if treated, ok := release.outliers.treatTimeToRestore(restoration.TimeToRestore); ok {
+				restoration.TimeToRestore = treated
+				restorations = append(restorations, restoration)
+			}
+		}
+	}
+	return restorations
+}
+
+// GetTimeToRestoreStatistics returns count, mean, median and max of time to restore and the restorations.
+func GetTimeToRestoreStatistics(releases []*Release) TimeToRestoreStatistics {
+	return newTimeToRestoreStatistics(GetRestorations(releases))
+}
+
+// GetWorkingTimeToRestoreStatistics returns statistics of time to restore counting only working time of calendar.
+// TimeToRestore of the restorations is also working time. Restorations dropped as outliers are excluded, but working time is not winsorized.
+func GetWorkingTimeToRestoreStatistics(releases []*Release, calendar *WorkingCalendar) TimeToRestoreStatistics {
+	restorations := GetRestorations(releases)
+	for i, restoration := range restorations {
+		restorations[i].TimeToRestore = calendar.WorkingDuration(restoration.Start, restoration.End)
+	}
+	return newTimeToRestoreStatistics(restorations)
+}
+
+func newTimeToRestoreStatistics(restorations []Restoration) TimeToRestoreStatistics {
+	timesToRestore := make([]time.Duration, 0, len(restorations))
+	for _, restoration := range restorations {
+		timesToRestore = append(timesToRestore, restoration.TimeToRestore)
+	}
+	return TimeToRestoreStatistics{
+		Count:        len(restorations),
+		Mean:         getMeanDuration(timesToRestore),
+		Median:       getPercentileDuration(timesToRestore, 50),
+		Max:          getPercentileDuration(timesToRestore, 100),
+		Restorations: restorations,
+	}
+}
+
+// GetTimeToRestore returns mean of time to restore.
+func GetTimeToRestore(releases []*Release) time.Duration {
+	return GetTimeToRestoreStatistics(releases).Mean
 }
 
 func GetChangeFailureRate(releases []*Release) float64 {
@@ -72,3 +223,18 @@ func GetChangeFailureRate(releases []*Release) float64 {
 	}
 	return float64(sumOfFailure) / float64(len(releases))
 }
+
+// GetReworkRate returns the rate of releases that are unplanned fixes.
+func GetReworkRate(releases []*Release) float64 {
+	if len(releases) == 0 {
+		return 0
+	}
+
+	sumOfRework := 0
+	for _, release := range releases {
+		if release.IsRework {
+			sumOfRework += 1
+		}
+	}
+	return float64(sumOfRework) / float64(len(releases))
+}
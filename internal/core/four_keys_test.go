@@ -0,0 +1,172 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetLeadTimeForChangesStatisticsShouldUseLeadTimeOfEachReleaseInFirstCommitMode(t *testing.T) {
+	releases := []*Release{
+		{Tag: "v3", LeadTimeForChanges: 30 * time.Hour},
+		{Tag: "v2", LeadTimeForChanges: 20 * time.Hour},
+		{Tag: "v1", LeadTimeForChanges: 10 * time.Hour},
+	}
+
+	actual := GetLeadTimeForChangesStatistics(releases, LeadTimeModeFirstCommit)
+
+	expected := LeadTimeForChangesStatistics{
+		Mean:   20 * time.Hour,
+		Median: 20 * time.Hour,
+		P75:    25 * time.Hour,
+		P90:    28 * time.Hour,
+		P95:    29 * time.Hour,
+	}
+	if actual != expected {
+		t.Errorf("statistics should be %+v but %+v", expected, actual)
+	}
+}
+
+func TestGetLeadTimeForChangesStatisticsShouldUseLeadTimeOfEachCommitInPerCommitMode(t *testing.T) {
+	date := time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC)
+	releases := []*Release{
+		{
+			Tag:                "v2",
+			Date:               date,
+			LeadTimeForChanges: 10 * 24 * time.Hour,
+			commits: []*Commit{
+				{CommitterWhen: date.AddDate(0, 0, -1)},
+				{CommitterWhen: date.AddDate(0, 0, -2)},
+				{CommitterWhen: date.AddDate(0, 0, -3)},
+				{CommitterWhen: date.AddDate(0, 0, -10)},
+			},
+		},
+	}
+
+	actual := GetLeadTimeForChangesStatistics(releases, LeadTimeModePerCommit)
+
+	day := 24 * time.Hour
+	expected := LeadTimeForChangesStatistics{
+		Mean:   4 * day,
+		Median: 2*day + 12*time.Hour,
+		P75:    4*day + 18*time.Hour,
+		P90:    7*day + 21*time.Hour + 36*time.Minute,
+		P95:    8*day + 22*time.Hour + 48*time.Minute,
+	}
+	if actual != expected {
+		t.Errorf("statistics should be %+v but %+v", expected, actual)
+	}
+}
+
+func TestGetLeadTimeForChangesStatisticsShouldBeZeroWithoutReleases(t *testing.T) {
+	actual := GetLeadTimeForChangesStatistics([]*Release{}, LeadTimeModePerCommit)
+
+	if actual != (LeadTimeForChangesStatistics{}) {
+		t.Errorf("statistics should be zero but %+v", actual)
+	}
+}
+
+// createReleasesOfHotfixScenario returns releases of the hotfix scenario in README.
+// v2 is failure and v3 hotfixes v2.
+func createReleasesOfHotfixScenario() []*Release {
+	releases := []*Release{
+		{Tag: "v3", Date: time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: 5 * 24 * time.Hour, commits: []*Commit{{Message: "hotfix"}}},
+		{Tag: "v2", Date: time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: 10 * 24 * time.Hour},
+		{Tag: "v1", Date: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	setReleaseResultForEachRelease(releases, nil)
+	return releases
+}
+
+func TestGetTimeToRestoreStatisticsShouldHaveRestorationOfHotfixScenario(t *testing.T) {
+	releases := createReleasesOfHotfixScenario()
+
+	actual := GetTimeToRestoreStatistics(releases)
+
+	days15 := 15 * 24 * time.Hour
+	if actual.Count != 1 || actual.Mean != days15 || actual.Median != days15 || actual.Max != days15 {
+		t.Errorf("statistics should have 1 restoration of %v but %+v", days15, actual)
+	}
+	if len(actual.Restorations) != 1 {
+		t.Fatalf("restorations should have 1 item but %v", actual.Restorations)
+	}
+	restoration := actual.Restorations[0]
+	if restoration.FailedRelease.Tag != "v2" || restoration.RestoringRelease.Tag != "v3" || restoration.TimeToRestore != days15 {
+		t.Errorf("v3 should restore v2 in %v but %v restores %v in %v", days15, restoration.RestoringRelease.Tag, restoration.FailedRelease.Tag, restoration.TimeToRestore)
+	}
+}
+
+func TestGetTimeToRestoreShouldReturnMeanOfRestorations(t *testing.T) {
+	releases := createReleasesOfHotfixScenario()
+	// v5 hotfixes v4 in 5 days
+	v5 := &Release{Tag: "v5", Date: time.Date(2023, 5, 15, 0, 0, 0, 0, time.UTC), commits: []*Commit{{Message: "hotfix"}}}
+	v4 := &Release{Tag: "v4", Date: time.Date(2023, 5, 10, 0, 0, 0, 0, time.UTC)}
+	releases = append([]*Release{v5, v4}, releases...)
+	setReleaseResultForEachRelease(releases, nil)
+
+	actual := GetTimeToRestore(releases)
+
+	expected := 10 * 24 * time.Hour
+	if actual != expected {
+		t.Errorf("time to restore should be mean of 15 days and 5 days (%v) but %v", expected, actual)
+	}
+	statistics := GetTimeToRestoreStatistics(releases)
+	if statistics.Count != 2 || statistics.Max != 15*24*time.Hour {
+		t.Errorf("statistics should have 2 restorations and max 15 days but %+v", statistics)
+	}
+}
+
+func TestGetTimeToRestoreStatisticsShouldBeZeroWithoutRestoration(t *testing.T) {
+	actual := GetTimeToRestoreStatistics([]*Release{{Tag: "v1", Result: ReleaseResult{IsSuccess: true}}})
+
+	if actual.Count != 0 || actual.Mean != 0 || actual.Median != 0 || actual.Max != 0 || len(actual.Restorations) != 0 {
+		t.Errorf("statistics should be zero but %+v", actual)
+	}
+}
+
+func TestGetDeploymentFrequencyByTimeunitShouldNormalizeByTimeunit(t *testing.T) {
+	releases := make([]*Release, 12)
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC)
+	testCases := []struct {
+		timeunit string
+		expected float64
+	}{
+		{"day", 12.0 / 364},
+		{"week", 12.0 / 52},
+		{"month", 12.0 / 11},
+		{"quarter", 12.0 / 3},
+		{"year", 12},
+		{"14d", 12.0 / 26},
+		{"2w", 12.0 / 26},
+		{"unknown", 12.0 / 364},
+	}
+	for _, testCase := range testCases {
+		actual := GetDeploymentFrequencyByTimeunit(releases, since, until, testCase.timeunit)
+		if actual != testCase.expected {
+			t.Errorf("frequency by %v should be %v but %v", testCase.timeunit, testCase.expected, actual)
+		}
+	}
+}
+
+func TestParseFixedTimeunitShouldParseDaysAndWeeks(t *testing.T) {
+	day := 24 * time.Hour
+	testCases := []struct {
+		timeunit string
+		expected time.Duration
+		ok       bool
+	}{
+		{"day", day, true},
+		{"week", 7 * day, true},
+		{"30d", 30 * day, true},
+		{"2w", 14 * day, true},
+		{"0d", 0, false},
+		{"month", 0, false},
+		{"12h", 0, false},
+	}
+	for _, testCase := range testCases {
+		actual, ok := ParseFixedTimeunit(testCase.timeunit)
+		if actual != testCase.expected || ok != testCase.ok {
+			t.Errorf("%v should be (%v, %v) but (%v, %v)", testCase.timeunit, testCase.expected, testCase.ok, actual, ok)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"os/exec"
+	"path"
 	"sort"
 	"strings"
 	"time"
@@ -9,41 +12,165 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage/filesystem"
 )
 
 var (
 	ErrNoNewerCommit  = errors.New("no newer commit")
 	ErrLogUnavailable = errors.New("repository log is unavailable")
+	ErrBranchNotFound = errors.New("branch is not found")
+	// ErrGitDirectoryUnavailable is returned if local git command cannot run for the repository
+	ErrGitDirectoryUnavailable = errors.New("git directory is unavailable")
 )
 
-// traverseCommits runs traversaler for each commits between olderCommit and newerCommit.
-// Order to traverse rely on git.LogOrderCommitterTime.
-// If older commit is null, it is assumed that older commit is the initial commit of this repository.
+// traverseCommits runs traversaler for each commits reachable from newerCommit but not from olderCommit.
+// It is the same commit set as `git log olderCommit..newerCommit`.
+// Order to traverse rely on committer time (newest first).
+// If older commit is null, all commits reachable from newerCommit are traversed.
 // It returns ErrNoNewerCommit if newer commit is null.
 // It returns ErrLogUnavailable if git log is unavailable.
 func traverseCommits(repository *git.Repository, olderCommit *object.Commit, newerCommit *object.Commit, traversaler func(c *object.Commit) error) error {
 	if newerCommit == nil {
 		return ErrNoNewerCommit
 	}
-	var since *time.Time
+	reachableFromOlder := make(map[plumbing.Hash]bool)
 	if olderCommit != nil {
-		tmp := olderCommit.Committer.When.Add(time.Second)
-		since = &tmp
+		iter, err := repository.Log(&git.LogOptions{
+			From: olderCommit.Hash,
+		})
+		if err != nil {
+			return ErrLogUnavailable
+		}
+		err = iter.ForEach(func(c *object.Commit) error {
+			reachableFromOlder[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return ErrLogUnavailable
+		}
+	}
+	return object.NewCommitIterCTime(newerCommit, reachableFromOlder, nil).ForEach(traversaler)
+}
+
+// touchesPaths returns true if commit changes any file that matches patterns from any of its parents.
+// It is the same condition as `git log --full-history -- :(glob)pattern`.
+func touchesPaths(commit *object.Commit, patterns []string) (bool, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return false, err
 	}
-	iter, err := repository.Log(&git.LogOptions{
-		From:  newerCommit.Hash,
-		Since: since,
-		Order: git.LogOrderCommitterTime,
+	if commit.NumParents() == 0 {
+		return treeTouchesPaths(nil, tree, patterns)
+	}
+	touches := false
+	err = commit.Parents().ForEach(func(parent *object.Commit) error {
+		parentTree, err := parent.Tree()
+		if err != nil {
+			return err
+		}
+		if touches, err = treeTouchesPaths(parentTree, tree, patterns); err != nil || touches {
+			return storer.ErrStop
+		}
+		return nil
 	})
 	if err != nil {
-		return ErrLogUnavailable
+		return false, err
 	}
-	return iter.ForEach(traversaler)
+	return touches, nil
+}
+
+// treeTouchesPaths returns true if any changed file from olderTree to newerTree matches patterns.
+// olderTree is nil for the root commit.
+func treeTouchesPaths(olderTree *object.Tree, newerTree *object.Tree, patterns []string) (bool, error) {
+	changes, err := object.DiffTree(olderTree, newerTree)
+	if err != nil {
+		return false, err
+	}
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" && matchesPathPatterns(patterns, name) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchesPathPatterns returns true if name or any of its parent directories matches any of patterns.
+// Patterns are glob of path.Match, and "**" matches zero or more directories.
+func matchesPathPatterns(patterns []string, name string) bool {
+	segments := strings.Split(name, "/")
+	for _, pattern := range patterns {
+		patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+		for i := 1; i <= len(segments); i++ {
+			if matchesPathSegments(patternSegments, segments[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesPathSegments(patternSegments []string, segments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(segments) == 0
+	}
+	if patternSegments[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchesPathSegments(patternSegments[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, err := path.Match(patternSegments[0], segments[0]); err != nil || !matched {
+		return false
+	}
+	return matchesPathSegments(patternSegments[1:], segments[1:])
+}
+
+// getGitDirectory returns the git directory of repository, e.g. ".git" of the worktree or the bare repository itself.
+// It returns ErrGitDirectoryUnavailable if repository is not on file system (e.g. in-memory repository).
+func getGitDirectory(repository *git.Repository) (string, error) {
+	if repository == nil {
+		return "", ErrGitDirectoryUnavailable
+	}
+	storage, ok := repository.Storer.(*filesystem.Storage)
+	if !ok {
+		return "", ErrGitDirectoryUnavailable
+	}
+	return storage.Filesystem().Root(), nil
+}
+
+// runLocalGit runs local git command for gitDirectory and returns its standard output.
+// The error has standard error of the command if the command fails.
+func runLocalGit(gitDirectory string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"--git-dir", gitDirectory}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("git %v: %v: %s", args[0], err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git %v: %v", args[0], err)
+	}
+	return output, nil
 }
 
 type ReleaseSource struct {
-	tag    *plumbing.Reference
+	// name is the tag name, or the abbreviated commit hash for untagged release
+	name   string
 	commit *object.Commit
+	// date is when the release is deployed
+	date time.Time
+}
+
+func newReleaseSource(name string, commit *object.Commit) ReleaseSource {
+	return ReleaseSource{name: name, commit: commit, date: commit.Committer.When}
 }
 
 func getReleaseSourcesFromTags(repository *git.Repository, tags []*plumbing.Reference) []ReleaseSource {
@@ -61,12 +188,77 @@ func getReleaseSourcesFromTags(repository *git.Repository, tags []*plumbing.Refe
 			}
 		}
 
-		sources = append(sources, ReleaseSource{tag: tag, commit: commit})
+		sources = append(sources, newReleaseSource(tag.Name().Short(), commit))
+	}
+	sortReleaseSources(sources)
+	return sources
+}
+
+// getReleaseSourcesFromBranch returns ReleaseSource of each merge commit on the first-parent history of branch.
+// branch is looked up from local branches and then remote branches of origin.
+// It returns ErrBranchNotFound if branch does not exist.
+func getReleaseSourcesFromBranch(repository *git.Repository, branch string) ([]ReleaseSource, error) {
+	sources := make([]ReleaseSource, 0)
+	var ref *plumbing.Reference
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(branch), plumbing.NewRemoteReferenceName("origin", branch)} {
+		if found, err := repository.Reference(name, true); err == nil {
+			ref = found
+			break
+		}
+	}
+	if ref == nil {
+		return sources, ErrBranchNotFound
 	}
+	commit, err := repository.CommitObject(ref.Hash())
+	if err != nil {
+		return sources, ErrLogUnavailable
+	}
+	for commit != nil {
+		if commit.NumParents() > 1 {
+			sources = append(sources, newReleaseSource(commit.Hash.String()[:7], commit))
+		}
+		if commit.NumParents() == 0 {
+			break
+		}
+		commit, err = commit.Parent(0)
+		if err != nil {
+			return sources, ErrLogUnavailable
+		}
+	}
+	sortReleaseSources(sources)
+	return sources, nil
+}
+
+// sortReleaseSources sorts sources by date (first item is the newest).
+func sortReleaseSources(sources []ReleaseSource) {
 	sort.Slice(sources, func(i, j int) bool {
-		return sources[i].commit.Committer.When.After(sources[j].commit.Committer.When)
+		return sources[i].date.After(sources[j].date)
 	})
-	return sources
+}
+
+// sortReleaseSourcesBySemanticVersion sorts sources by semantic version of name (first item is the newest).
+// Component prefix of option.ComponentPattern is removed from name to parse version.
+// Sources whose name is not semantic version are removed.
+// Sources of the same version are sorted by date.
+func sortReleaseSourcesBySemanticVersion(sources []ReleaseSource, option *Option) []ReleaseSource {
+	versionedSources := make([]ReleaseSource, 0)
+	versions := make(map[string]*SemanticVersion)
+	for _, source := range sources {
+		version, err := parseSemanticVersion(option.versionOf(source.name))
+		if err != nil {
+			option.Debugln(source.name, "is ignored for not semantic version")
+			continue
+		}
+		versions[source.name] = version
+		versionedSources = append(versionedSources, source)
+	}
+	sort.SliceStable(versionedSources, func(i, j int) bool {
+		if result := versions[versionedSources[i].name].Compare(versions[versionedSources[j].name]); result != 0 {
+			return result > 0
+		}
+		return versionedSources[i].date.After(versionedSources[j].date)
+	})
+	return versionedSources
 }
 
 func QueryTags(repository *git.Repository) []*plumbing.Reference {
@@ -1,12 +1,19 @@
 package core
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/hmiyado/four-keys/internal/util"
+)
 
 func TestQueryTagsShouldHaveTags(t *testing.T) {
 	tags := QueryTags(repository)
-	expectedTagNum := 60
+	expectedTagNum := 5
 
-	if len(tags) < expectedTagNum {
+	if len(tags) != expectedTagNum {
 		for i := 0; i < len(tags); i++ {
 			if tags[i] == nil {
 				t.Logf("tags[%d] = nil", i)
@@ -35,12 +42,85 @@ func TestQueryTagsShouldReturnEmptyForEmptyRepository(t *testing.T) {
 }
 
 func TestQueryTagsShouldRecognizeTagObject(t *testing.T) {
-	tags := QueryTags(repositoryCli)
-	expectedTagsCount := 77
+	tags := QueryTags(repositoryAnnotatedTags)
+	expectedTagsCount := 3
 
-	if len(tags) < expectedTagsCount {
+	if len(tags) != expectedTagsCount {
 		// if QueryTags can't recognize tag object,
-		// tags count becomes much smaller than expectedTagsCount
-		t.Errorf("cli repository should have over %v tags but %v", expectedTagsCount, len(tags))
+		// tags count becomes smaller than expectedTagsCount
+		t.Errorf("repository should have %v tags but %v", expectedTagsCount, len(tags))
+	}
+}
+
+func TestTraverseCommitsShouldTraverseCommitsReachableFromNewerButNotFromOlder(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "m0" date: "2023-03-01"
+  branch develop
+  %% long-lived branch whose commit is older than m1
+  commit id: "d1" date: "2023-03-25"
+  checkout main
+  commit id: "m1" date: "2023-04-01"
+  %% commit with skewed clock which is newer than m3 but already released by m2
+  commit id: "m2" date: "2023-04-20" tag: "older"
+  merge develop id: "m3" date: "2023-04-15" tag: "newer"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make([]string, 0)
+	err = traverseCommits(r, commitOfTagForTest(t, r, "older"), commitOfTagForTest(t, r, "newer"), func(c *object.Commit) error {
+		messages = append(messages, c.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"m3", "d1"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("traversed commits should be %v but %v", expected, messages)
+	}
+}
+
+func TestTraverseCommitsShouldTraverseAllCommitsWithoutOlderCommit(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "m1" date: "2023-04-01"
+  commit id: "m2" date: "2023-04-02" tag: "newer"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make([]string, 0)
+	err = traverseCommits(r, nil, commitOfTagForTest(t, r, "newer"), func(c *object.Commit) error {
+		messages = append(messages, c.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"m2", "m1"}
+	if !reflect.DeepEqual(messages, expected) {
+		t.Errorf("traversed commits should be %v but %v", expected, messages)
+	}
+}
+
+func TestTraverseCommitsShouldReturnErrorWithoutNewerCommit(t *testing.T) {
+	err := traverseCommits(emptyRepository, nil, nil, func(c *object.Commit) error { return nil })
+	if err != ErrNoNewerCommit {
+		t.Errorf("error should be %v but %v", ErrNoNewerCommit, err)
+	}
+}
+
+func commitOfTagForTest(t *testing.T, repository *git.Repository, name string) *object.Commit {
+	t.Helper()
+	tag, err := repository.Tag(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repository.CommitObject(tag.Hash())
+	if err != nil {
+		t.Fatal(err)
 	}
+	return commit
 }
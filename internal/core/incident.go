@@ -0,0 +1,58 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Incident is a failure in production caused by a release.
+type Incident struct {
+	Start    time.Time `json:"start" yaml:"start"`
+	Resolved time.Time `json:"resolved" yaml:"resolved"`
+	// Release is the tag or the (abbreviated) commit hash of the release that caused the incident
+	Release string `json:"release" yaml:"release"`
+	// Severity is used to filter incidents by Option.IncidentSeverities
+	Severity string `json:"severity" yaml:"severity"`
+}
+
+// ReadIncidents reads a list of incidents from path.
+// The file is YAML if its extension is ".yaml" or ".yml", otherwise JSON.
+func ReadIncidents(path string) ([]Incident, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	extension := strings.ToLower(filepath.Ext(path))
+	return parseIncidents(data, extension == ".yaml" || extension == ".yml")
+}
+
+func parseIncidents(data []byte, isYaml bool) ([]Incident, error) {
+	incidents := make([]Incident, 0)
+	var err error
+	if isYaml {
+		err = yaml.Unmarshal(data, &incidents)
+	} else {
+		err = json.Unmarshal(data, &incidents)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, incident := range incidents {
+		if incident.Release == "" {
+			return nil, fmt.Errorf("incidents[%v]: release is required", i)
+		}
+		if incident.Start.IsZero() || incident.Resolved.IsZero() {
+			return nil, fmt.Errorf("incidents[%v]: start and resolved are required", i)
+		}
+		if incident.Resolved.Before(incident.Start) {
+			return nil, fmt.Errorf("incidents[%v]: resolved should not be before start", i)
+		}
+	}
+	return incidents, nil
+}
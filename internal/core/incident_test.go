@@ -0,0 +1,136 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+func TestParseIncidentsShouldReadYaml(t *testing.T) {
+	incidents, err := parseIncidents([]byte(`
+- start: 2023-05-21T00:00:00Z
+  resolved: 2023-05-21T06:00:00Z
+  release: v2.1.0
+  severity: critical
+- start: 2023-04-16T09:00:00+09:00
+  resolved: 2023-04-17T00:00:00Z
+  release: 0123abcd
+`), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(incidents) != 2 {
+		t.Fatalf("incidents should have 2 items but %v", incidents)
+	}
+	if incidents[0].Release != "v2.1.0" || incidents[0].Severity != "critical" || incidents[0].Resolved.Sub(incidents[0].Start) != 6*time.Hour {
+		t.Errorf("incidents[0] should be critical incident of v2.1.0 for 6 hours but %v", incidents[0])
+	}
+	if !incidents[1].Start.Equal(time.Date(2023, 4, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("incidents[1] should start at 2023-04-16T00:00:00Z but %v", incidents[1].Start)
+	}
+}
+
+func TestParseIncidentsShouldReadJson(t *testing.T) {
+	incidents, err := parseIncidents([]byte(`[{"start":"2023-05-21T00:00:00Z","resolved":"2023-05-21T06:00:00Z","release":"v2.1.0","severity":"minor"}]`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(incidents) != 1 || incidents[0].Release != "v2.1.0" || incidents[0].Severity != "minor" {
+		t.Errorf("incidents should have minor incident of v2.1.0 but %v", incidents)
+	}
+}
+
+func TestParseIncidentsShouldFailWhenResolvedBeforeStart(t *testing.T) {
+	_, err := parseIncidents([]byte(`[{"start":"2023-05-21T00:00:00Z","resolved":"2023-05-20T00:00:00Z","release":"v2.1.0"}]`), false)
+	if err == nil || !strings.Contains(err.Error(), "incidents[0]") {
+		t.Errorf("incident resolved before start should be error but %v", err)
+	}
+}
+
+func TestQueryReleasesShouldDetectFailureByIncidents(t *testing.T) {
+	releases := QueryReleases(repository, &Option{
+		Since:         time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+		FailureSource: FailureSourceIncidents,
+		Incidents: []Incident{
+			{Start: time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC), Resolved: time.Date(2023, 5, 21, 6, 0, 0, 0, time.UTC), Release: "v2.1.0"},
+		},
+	})
+
+	expected := []*Release{
+		{Tag: "v2.1.0", Date: time.Date(2023, 5, 20, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: time.Duration(0), Result: ReleaseResult{IsSuccess: false}},
+		{Tag: "v2.0.1", Date: time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("120h"), Result: ReleaseResult{IsSuccess: true}},
+		{Tag: "v2.0.0", Date: time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("240h"), Result: ReleaseResult{IsSuccess: true}},
+		{Tag: "v1.0.0", Date: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: time.Duration(0), Result: ReleaseResult{IsSuccess: true}},
+	}
+	assertReleasesAreEqual(t, expected, releases)
+	statistics := GetTimeToRestoreStatistics(releases)
+	if statistics.Count != 1 || statistics.Restorations[0].FailedRelease.Tag != "v2.1.0" || statistics.Restorations[0].RestoringRelease != nil || statistics.Mean != 6*time.Hour {
+		t.Errorf("v2.1.0 should be restored by incident resolution in 6h but %+v", statistics)
+	}
+	// rework is detected by failure detectors even if failures are incidents
+	for _, release := range releases {
+		if release.IsRework != (release.Tag == "v2.0.1") {
+			t.Errorf("only v2.0.1 that has hotfix commits should be rework but %v is %v", release.Tag, release.IsRework)
+		}
+	}
+	util.AssertIsNearBy(t, GetReworkRate(releases), 0.25, 0.01)
+}
+
+func TestQueryReleasesShouldCountEachIncidentOfRelease(t *testing.T) {
+	incidents := []Incident{
+		{Start: time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC), Resolved: time.Date(2023, 5, 21, 6, 0, 0, 0, time.UTC), Release: "v2.1.0", Severity: "critical"},
+		{Start: time.Date(2023, 5, 22, 0, 0, 0, 0, time.UTC), Resolved: time.Date(2023, 5, 22, 2, 0, 0, 0, time.UTC), Release: "v2.1.0", Severity: "minor"},
+	}
+	testCases := []struct {
+		severities    []string
+		expectedCount int
+		expectedMean  time.Duration
+	}{
+		{nil, 2, 4 * time.Hour},
+		{[]string{"Critical"}, 1, 6 * time.Hour},
+		{[]string{"major"}, 0, 0},
+	}
+	for _, testCase := range testCases {
+		releases := QueryReleases(repository, &Option{
+			Since:              time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+			Until:              time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+			FailureSource:      FailureSourceIncidents,
+			Incidents:          incidents,
+			IncidentSeverities: testCase.severities,
+		})
+		statistics := GetTimeToRestoreStatistics(releases)
+		if statistics.Count != testCase.expectedCount || statistics.Mean != testCase.expectedMean {
+			t.Errorf("severities %v: restorations should be %v with mean %v but %+v", testCase.severities, testCase.expectedCount, testCase.expectedMean, statistics)
+		}
+		if releases[0].Result.IsSuccess != (testCase.expectedCount == 0) || releases[0].Result.TimeToRestore != nil {
+			t.Errorf("severities %v: v2.1.0 should be failure without time to restore of its own but %v", testCase.severities, releases[0].Result)
+		}
+		if len(releases[0].Restorations()) != testCase.expectedCount {
+			t.Errorf("severities %v: restorations of v2.1.0 should be %v but %v", testCase.severities, testCase.expectedCount, releases[0].Restorations())
+		}
+	}
+}
+
+func TestQueryReleasesShouldDetectFailureByIncidentsAndFixCommitsInCombinedMode(t *testing.T) {
+	tag2_1_0, _ := repository.Tag("v2.1.0")
+	releases := QueryReleases(repository, &Option{
+		Since:         time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+		FailureSource: FailureSourceCombined,
+		Incidents: []Incident{
+			{Start: time.Date(2023, 5, 21, 0, 0, 0, 0, time.UTC), Resolved: time.Date(2023, 5, 21, 6, 0, 0, 0, time.UTC), Release: tag2_1_0.Hash().String()[:8]},
+		},
+	})
+
+	if GetChangeFailureRate(releases) != 0.5 {
+		t.Errorf("v2.1.0 and v2.0.0 should be failure but %v", releases)
+	}
+	if GetTimeToRestoreStatistics(releases).Count != 2 {
+		t.Errorf("restorations should be by incident and by hotfix but %v", GetRestorations(releases))
+	}
+}
@@ -6,17 +6,118 @@ import (
 	"time"
 )
 
+// LeadTimeMode is how lead time for changes is measured
+type LeadTimeMode string
+
+const (
+	// LeadTimeModeFirstCommit measures lead time of each release from its oldest commit
+	LeadTimeModeFirstCommit LeadTimeMode = "first-commit"
+	// LeadTimeModePerCommit measures lead time of each commit shipped by releases
+	LeadTimeModePerCommit LeadTimeMode = "per-commit"
+)
+
+// ReleaseSourceKind is what is regarded as a release
+type ReleaseSourceKind string
+
+const (
+	// ReleaseSourceTag regards each tag as a release
+	ReleaseSourceTag ReleaseSourceKind = "tag"
+	// ReleaseSourceBranch regards each merge commit on Option.ReleaseBranch as a release
+	ReleaseSourceBranch ReleaseSourceKind = "branch"
+	// ReleaseSourceDeployment regards each successful deployment of Option.Deployments as a release
+	ReleaseSourceDeployment ReleaseSourceKind = "deployment"
+)
+
+// ReleaseOrder is how releases are ordered to find the previous release of each release
+type ReleaseOrder string
+
+const (
+	// ReleaseOrderDate orders releases by date
+	ReleaseOrderDate ReleaseOrder = "date"
+	// ReleaseOrderSemver orders tags by precedence of semantic version. Tags that are not semantic version are excluded
+	ReleaseOrderSemver ReleaseOrder = "semver"
+)
+
+// PrereleaseMode is how releases of pre-release version (e.g. v2.0.0-rc.1) are handled
+type PrereleaseMode string
+
+const (
+	// PrereleaseModeInclude regards pre-releases as releases
+	PrereleaseModeInclude PrereleaseMode = "include"
+	// PrereleaseModeExclude does not regard pre-releases as releases, but each of them still starts the commits of the subsequent release.
+	// Commits shipped by pre-releases are not counted
+	PrereleaseModeExclude PrereleaseMode = "exclude"
+	// PrereleaseModeCollapse folds pre-releases into the subsequent final release
+	PrereleaseModeCollapse PrereleaseMode = "collapse"
+)
+
+// FailureSource is what is used to detect failure of releases
+type FailureSource string
+
+const (
+	// FailureSourceFixCommits regards a release as failure if any of Option.FailureDetectors detects it
+	FailureSourceFixCommits FailureSource = "commits"
+	// FailureSourceIncidents regards a release as failure if it caused an incident of Option.Incidents
+	FailureSourceIncidents FailureSource = "incidents"
+	// FailureSourceCombined uses both of fix commits and incidents
+	FailureSourceCombined FailureSource = "combined"
+)
+
 type Option struct {
 	// inclucive
 	Since time.Time `json:"since"`
 	// inclucive
-	Until             time.Time      `json:"until"`
-	IgnorePattern     *regexp.Regexp `json:"-"`
+	Until         time.Time      `json:"until"`
+	IgnorePattern *regexp.Regexp `json:"-"`
+	// TagPattern includes only tags that match the pattern. All tags are included if nil
+	TagPattern *regexp.Regexp `json:"-"`
+	// ReleaseOrder defaults to ReleaseOrderDate. It is used when ReleaseSource is ReleaseSourceTag
+	ReleaseOrder ReleaseOrder `json:"-"`
+	// Prereleases defaults to PrereleaseModeInclude
+	Prereleases PrereleaseMode `json:"-"`
+	// ComponentPattern enables monorepo mode. The first group of the pattern (or the whole match if no group) is the component of a release.
+	// e.g. `^([^/]+)/` regards "payments/v1.4.0" as a release of payments. Releases that do not match are ignored
+	ComponentPattern *regexp.Regexp `json:"-"`
+	// Paths are glob patterns of files. Only commits that touch them are used for lead time and failures. All commits are used if empty
+	Paths             []string       `json:"-"`
 	FixCommitPattern  *regexp.Regexp `json:"-"`
 	IsLocalRepository bool           `json:"-"`
-	StartTimerFunc    func(string)   `json:"-"`
-	StopTimerFunc     func(string)   `json:"-"`
-	DebuglnFunc       func(...any)   `json:"-"`
+	LeadTimeMode      LeadTimeMode   `json:"-"`
+	// ExcludeInitialRelease excludes lead time for changes of the release that has no previous release,
+	// because it is measured from the first commit of the repository
+	ExcludeInitialRelease bool `json:"-"`
+	// Outliers defaults to OutlierModeNone
+	Outliers OutlierMode `json:"-"`
+	// OutlierMultiplier is N of the fences Q1 - N×IQR and Q3 + N×IQR. DefaultOutlierMultiplier is used if zero
+	OutlierMultiplier float64           `json:"-"`
+	ReleaseSource     ReleaseSourceKind `json:"-"`
+	// ReleaseBranch is the branch to find merge commits when ReleaseSource is ReleaseSourceBranch
+	ReleaseBranch string `json:"-"`
+	// Deployments are used when ReleaseSource is ReleaseSourceDeployment
+	Deployments []Deployment `json:"-"`
+	// DeploymentEnvironment filters Deployments by environment. All environments are used if empty
+	DeploymentEnvironment string `json:"-"`
+	// FailureSource defaults to FailureSourceFixCommits
+	FailureSource FailureSource `json:"-"`
+	// Incidents are used when FailureSource is FailureSourceIncidents or FailureSourceCombined
+	Incidents []Incident `json:"-"`
+	// IncidentSeverities filters Incidents by severity. All incidents are used if empty
+	IncidentSeverities []string `json:"-"`
+	// FailureDetectors are used when FailureSource is FailureSourceFixCommits or FailureSourceCombined.
+	// FixCommitDetector with FixCommitPattern is used if empty
+	FailureDetectors []FailureDetector `json:"-"`
+	// Location is the timezone of release dates. Release dates keep the offset of each commit if nil
+	Location *time.Location `json:"-"`
+	// WorkingCalendar is used to measure lead time for changes and time to restore in working time. They are not measured if nil
+	WorkingCalendar *WorkingCalendar `json:"-"`
+	// CountsChangedLines counts FilesChanged, LinesAdded and LinesDeleted of BatchSize of each release.
+	// They are zero if false, because the diff of each release is read to count them
+	CountsChangedLines bool `json:"-"`
+	// ReleaseCache is used to skip walking history of cached releases. History of all releases is walked if nil
+	ReleaseCache   *ReleaseCache `json:"-"`
+	StartTimerFunc func(string)  `json:"-"`
+	StopTimerFunc  func(string)  `json:"-"`
+	DebuglnFunc    func(...any)  `json:"-"`
 }
 
 func (o *Option) isInTimeRange(time time.Time) bool {
@@ -33,12 +134,96 @@ func (o *Option) shouldIgnore(name string) bool {
 	return o.IgnorePattern.MatchString(name)
 }
 
-func (o *Option) isFixedCommit(commitMessage string) bool {
-	if o == nil || o.FixCommitPattern == nil {
-		// commitMessage with "hotfix" is regarded as fixed commit by default
-		return strings.Contains(commitMessage, "hotfix")
+func (o *Option) includesTag(name string) bool {
+	if o == nil || o.TagPattern == nil {
+		return true
+	}
+	return o.TagPattern.MatchString(name)
+}
+
+func (o *Option) excludesPrerelease(name string) bool {
+	return o != nil && o.Prereleases == PrereleaseModeExclude && isPrereleaseName(o.versionOf(name))
+}
+
+// componentOf returns the component of name by ComponentPattern.
+func (o *Option) componentOf(name string) (string, bool) {
+	if o == nil || o.ComponentPattern == nil {
+		return "", false
+	}
+	matches := o.ComponentPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return "", false
+	}
+	if len(matches) > 1 {
+		return matches[1], true
+	}
+	return matches[0], true
+}
+
+// versionOf returns name without the part that matches ComponentPattern.
+func (o *Option) versionOf(name string) string {
+	if o == nil || o.ComponentPattern == nil {
+		return name
 	}
-	return o.FixCommitPattern.MatchString(commitMessage)
+	location := o.ComponentPattern.FindStringIndex(name)
+	if location == nil {
+		return name
+	}
+	return name[:location[0]] + name[location[1]:]
+}
+
+func (o *Option) paths() []string {
+	if o == nil {
+		return nil
+	}
+	return o.Paths
+}
+
+func (o *Option) includesIncidentSeverity(severity string) bool {
+	if o == nil || len(o.IncidentSeverities) == 0 {
+		return true
+	}
+	for _, includedSeverity := range o.IncidentSeverities {
+		if strings.EqualFold(severity, includedSeverity) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Option) releaseCache() *ReleaseCache {
+	if o == nil {
+		return nil
+	}
+	return o.ReleaseCache
+}
+
+func (o *Option) failureDetectors() []FailureDetector {
+	if o == nil {
+		return []FailureDetector{FixCommitDetector{}}
+	}
+	if len(o.FailureDetectors) == 0 {
+		return []FailureDetector{FixCommitDetector{Pattern: o.FixCommitPattern}}
+	}
+	return o.FailureDetectors
+}
+
+// IsFixCommit returns true if any of failure detectors regards commit as a fix commit.
+func (o *Option) IsFixCommit(commit *Commit) bool {
+	for _, detector := range o.failureDetectors() {
+		if detector, ok := detector.(fixCommitDetector); ok && detector.isFixCommit(commit) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Option) detectsFailureByFixCommits() bool {
+	return o == nil || o.FailureSource != FailureSourceIncidents
+}
+
+func (o *Option) detectsFailureByIncidents() bool {
+	return o != nil && (o.FailureSource == FailureSourceIncidents || o.FailureSource == FailureSourceCombined)
 }
 
 func (o *Option) StartTimer(key string) {
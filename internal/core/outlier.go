@@ -0,0 +1,141 @@
+package core
+
+import "time"
+
+// OutlierMode is how outliers of lead time for changes and time to restore are treated
+type OutlierMode string
+
+const (
+	// OutlierModeNone uses all values as they are
+	OutlierModeNone OutlierMode = "none"
+	// OutlierModeWinsorize replaces values beyond the fences with the nearest fence
+	OutlierModeWinsorize OutlierMode = "winsorize"
+	// OutlierModeDrop excludes values beyond the fences
+	OutlierModeDrop OutlierMode = "drop"
+)
+
+// DefaultOutlierMultiplier is N of the fences Q1 - N×IQR and Q3 + N×IQR, which is the Tukey's fences
+const DefaultOutlierMultiplier = 1.5
+
+// outlierFences are the lower and upper fences of values. Values beyond them are outliers.
+type outlierFences struct {
+	lower time.Duration
+	upper time.Duration
+}
+
+// newOutlierFences returns fences Q1 - multiplier×IQR and Q3 + multiplier×IQR of values.
+func newOutlierFences(values []time.Duration, multiplier float64) outlierFences {
+	q1 := getPercentileDuration(values, 25)
+	q3 := getPercentileDuration(values, 75)
+	margin := time.Duration(multiplier * float64(q3-q1))
+	return outlierFences{lower: q1 - margin, upper: q3 + margin}
+}
+
+// treat returns the value treated by mode, and false if the value is dropped.
+func (f outlierFences) treat(value time.Duration, mode OutlierMode) (time.Duration, bool) {
+	if value >= f.lower && value <= f.upper {
+		return value, true
+	}
+	switch mode {
+	case OutlierModeWinsorize:
+		if value < f.lower {
+			return f.lower, true
+		}
+		return f.upper, true
+	case OutlierModeDrop:
+		return value, false
+	default:
+		return value, true
+	}
+}
+
+// isOutlier returns true if the value is beyond the fences.
+func (f outlierFences) isOutlier(value time.Duration) bool {
+	return value < f.lower || value > f.upper
+}
+
+// outlierTreatment is how outliers of releases queried together are treated.
+// Fences are shared by the releases, so that any subset of them (e.g. an interval of time series) is treated in the same way.
+type outlierTreatment struct {
+	mode          OutlierMode
+	leadTime      outlierFences
+	timeToRestore outlierFences
+}
+
+// treatLeadTime returns the lead time treated by the treatment, and false if it is dropped.
+// The lead time is returned as is if the treatment is nil.
+func (t *outlierTreatment) treatLeadTime(leadTime time.Duration) (time.Duration, bool) {
+	if t == nil {
+		return leadTime, true
+	}
+	return t.leadTime.treat(leadTime, t.mode)
+}
+
+// treatTimeToRestore returns the time to restore treated by the treatment, and false if it is dropped.
+// The time to restore is returned as is if the treatment is nil.
+func (t *outlierTreatment) treatTimeToRestore(timeToRestore time.Duration) (time.Duration, bool) {
+	if t == nil {
+		return timeToRestore, true
+	}
+	return t.timeToRestore.treat(timeToRestore, t.mode)
+}
+
+// setOutliers excludes lead time of the initial release if option.ExcludeInitialRelease,
+// and treats outliers of lead time for changes and time to restore by option.Outliers.
+// Releases that have excluded or treated values are marked as outlier.
+func setOutliers(releases []*Release, option *Option) {
+	for _, release := range releases {
+		release.IsOutlier = false
+		release.isLeadTimeExcluded = false
+		release.outliers = nil
+	}
+	if option == nil {
+		return
+	}
+	if option.ExcludeInitialRelease {
+		for _, release := range releases {
+			if release.isInitial {
+				release.IsOutlier = true
+				release.isLeadTimeExcluded = true
+			}
+		}
+	}
+	if option.Outliers == "" || option.Outliers == OutlierModeNone {
+		return
+	}
+
+	multiplier := option.OutlierMultiplier
+	if multiplier <= 0 {
+		multiplier = DefaultOutlierMultiplier
+	}
+	leadTimes := make([]time.Duration, 0)
+	timesToRestore := make([]time.Duration, 0)
+	for _, release := range releases {
+		if !release.isLeadTimeExcluded {
+			leadTimes = append(leadTimes, release.LeadTimesForChanges(option.LeadTimeMode)...)
+		}
+		for _, restoration := range release.restorations {
+			timesToRestore = append(timesToRestore, restoration.TimeToRestore)
+		}
+	}
+	treatment := &outlierTreatment{
+		mode:          option.Outliers,
+		leadTime:      newOutlierFences(leadTimes, multiplier),
+		timeToRestore: newOutlierFences(timesToRestore, multiplier),
+	}
+	for _, release := range releases {
+		release.outliers = treatment
+		if !release.isLeadTimeExcluded {
+			for _, leadTime := range release.LeadTimesForChanges(option.LeadTimeMode) {
+				if treatment.leadTime.isOutlier(leadTime) {
+					release.IsOutlier = true
+				}
+			}
+		}
+		for _, restoration := range release.restorations {
+			if treatment.timeToRestore.isOutlier(restoration.TimeToRestore) {
+				release.IsOutlier = true
+			}
+		}
+	}
+}
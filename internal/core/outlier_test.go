@@ -0,0 +1,115 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+// newRepositoryWithInitialRelease returns a repository whose initial release v1 has lead time of 424 days from the first commit,
+// and lead times of v2, v3, v4 and v5 are 1, 1, 1 and 2 days.
+func newRepositoryWithInitialRelease(t *testing.T) *git.Repository {
+	day := func(month time.Month, day int) time.Time {
+		return time.Date(2023, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	repository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).
+		Commit("first commit", time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)).
+		Commit("c1", day(3, 1)).Tag("v1").
+		Commit("c2", day(3, 2)).Commit("c3", day(3, 3)).Tag("v2").
+		Commit("c4", day(3, 4)).Commit("c5", day(3, 5)).Tag("v3").
+		Commit("c6", day(3, 6)).Commit("c7", day(3, 7)).Tag("v4").
+		Commit("c8", day(3, 8)).Commit("c9", day(3, 10)).Tag("v5").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repository
+}
+
+func TestQueryReleasesShouldTreatOutliersOfLeadTime(t *testing.T) {
+	repository := newRepositoryWithInitialRelease(t)
+	day := 24 * time.Hour
+	testCases := []struct {
+		name                  string
+		excludeInitialRelease bool
+		outliers              OutlierMode
+		expectedMean          time.Duration
+		expectedCount         int
+	}{
+		{"none", false, OutlierModeNone, (424 + 1 + 1 + 1 + 2) * day / 5, 5},
+		{"exclude initial release", true, OutlierModeNone, (1 + 1 + 1 + 2) * day / 4, 4},
+		// Q1 = 1d and Q3 = 2d, so that the fences are -0.5d and 3.5d
+		{"drop", false, OutlierModeDrop, (1 + 1 + 1 + 2) * day / 4, 4},
+		{"winsorize", false, OutlierModeWinsorize, time.Duration(3.5*float64(day)+5*float64(day)) / 5, 5},
+	}
+	for _, testCase := range testCases {
+		for _, isLocalRepository := range []bool{true, false} {
+			option := &Option{
+				Since:                 time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				Until:                 time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+				IsLocalRepository:     isLocalRepository,
+				ExcludeInitialRelease: testCase.excludeInitialRelease,
+				Outliers:              testCase.outliers,
+			}
+			releases := QueryReleases(repository, option)
+			if len(releases) != 5 {
+				t.Fatalf("%v: releases should be v1 to v5 but %v", testCase.name, releases)
+			}
+			if releases[4].LeadTimeForChanges != 424*day {
+				t.Errorf("%v: lead time of v1 should be kept as 424 days but %v", testCase.name, releases[4].LeadTimeForChanges)
+			}
+			leadTimes := GetLeadTimesForChanges(releases, LeadTimeModeFirstCommit)
+			if len(leadTimes) != testCase.expectedCount {
+				t.Errorf("%v (local: %v): lead times should be %v samples but %v", testCase.name, isLocalRepository, testCase.expectedCount, leadTimes)
+			}
+			if mean := GetLeadTimeForChangesStatistics(releases, LeadTimeModeFirstCommit).Mean; mean != testCase.expectedMean {
+				t.Errorf("%v (local: %v): mean of lead time should be %v but %v", testCase.name, isLocalRepository, testCase.expectedMean, mean)
+			}
+			for i, release := range releases {
+				expectedIsOutlier := i == 4 && testCase.outliers != OutlierModeNone || i == 4 && testCase.excludeInitialRelease
+				if release.IsOutlier != expectedIsOutlier {
+					t.Errorf("%v (local: %v): IsOutlier of %v should be %v", testCase.name, isLocalRepository, release.Tag, expectedIsOutlier)
+				}
+			}
+		}
+	}
+}
+
+func TestGetRestorationsShouldTreatOutliersOfTimeToRestore(t *testing.T) {
+	newReleases := func() []*Release {
+		releases := make([]*Release, 0)
+		// times to restore are 1h, 2h, 1h and 100h, so that the upper fence is 64.75h
+		for _, timeToRestore := range []time.Duration{time.Hour, 2 * time.Hour, time.Hour, 100 * time.Hour} {
+			release := &Release{}
+			release.restorations = []Restoration{{RestoringRelease: release, TimeToRestore: timeToRestore}}
+			releases = append(releases, release)
+		}
+		return releases
+	}
+
+	dropped := newReleases()
+	setOutliers(dropped, &Option{Outliers: OutlierModeDrop})
+	statistics := GetTimeToRestoreStatistics(dropped)
+	if statistics.Count != 3 || statistics.Max != 2*time.Hour {
+		t.Errorf("100h should be dropped but %+v", statistics)
+	}
+	if !dropped[3].IsOutlier || dropped[0].IsOutlier {
+		t.Errorf("only release restored in 100h should be outlier but %v", dropped)
+	}
+
+	winsorized := newReleases()
+	setOutliers(winsorized, &Option{Outliers: OutlierModeWinsorize})
+	statistics = GetTimeToRestoreStatistics(winsorized)
+	if statistics.Count != 4 || statistics.Max != time.Duration(64.75*float64(time.Hour)) {
+		t.Errorf("100h should be winsorized into 64.75h but %+v", statistics)
+	}
+
+	// the upper fence is 103h with multiplier 3
+	loose := newReleases()
+	setOutliers(loose, &Option{Outliers: OutlierModeDrop, OutlierMultiplier: 3})
+	if statistics := GetTimeToRestoreStatistics(loose); statistics.Count != 4 || loose[3].IsOutlier {
+		t.Errorf("100h should be kept with multiplier 3 but %+v", statistics)
+	}
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PerformanceTier is a performance cluster of DORA State of DevOps
+type PerformanceTier string
+
+const (
+	PerformanceTierElite  PerformanceTier = "elite"
+	PerformanceTierHigh   PerformanceTier = "high"
+	PerformanceTierMedium PerformanceTier = "medium"
+	PerformanceTierLow    PerformanceTier = "low"
+	// PerformanceTierUnknown is the tier of a metric without data, e.g. lead time without releases
+	PerformanceTierUnknown PerformanceTier = "unknown"
+)
+
+// performanceTierOrder is the order of tiers from the best
+var performanceTierOrder = []PerformanceTier{PerformanceTierElite, PerformanceTierHigh, PerformanceTierMedium, PerformanceTierLow}
+
+// FrequencyThreshold is the minimum deployments per day of each tier.
+// A frequency less than Medium is Low.
+type FrequencyThreshold struct {
+	Elite  float64 `json:"elite" yaml:"elite"`
+	High   float64 `json:"high" yaml:"high"`
+	Medium float64 `json:"medium" yaml:"medium"`
+}
+
+// DurationThreshold is the maximum duration of each tier.
+// A duration longer than Medium is Low.
+type DurationThreshold struct {
+	Elite  time.Duration `json:"elite" yaml:"elite"`
+	High   time.Duration `json:"high" yaml:"high"`
+	Medium time.Duration `json:"medium" yaml:"medium"`
+}
+
+// UnmarshalJSON reads each duration from a string of time.ParseDuration (e.g. "168h") or nanoseconds.
+func (t *DurationThreshold) UnmarshalJSON(data []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	for name, duration := range map[string]*time.Duration{"elite": &t.Elite, "high": &t.High, "medium": &t.Medium} {
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+		var durationString string
+		if err := json.Unmarshal(value, &durationString); err != nil {
+			if err := json.Unmarshal(value, duration); err != nil {
+				return fmt.Errorf("%v: %v", name, err)
+			}
+			continue
+		}
+		parsedDuration, err := time.ParseDuration(durationString)
+		if err != nil {
+			return fmt.Errorf("%v: %v", name, err)
+		}
+		*duration = parsedDuration
+	}
+	return nil
+}
+
+// RateThreshold is the maximum rate of each tier.
+// A rate higher than Medium is Low.
+type RateThreshold struct {
+	Elite  float64 `json:"elite" yaml:"elite"`
+	High   float64 `json:"high" yaml:"high"`
+	Medium float64 `json:"medium" yaml:"medium"`
+}
+
+// PerformanceThresholds are thresholds to classify each of four keys into PerformanceTier.
+type PerformanceThresholds struct {
+	DeploymentFrequency FrequencyThreshold `json:"deploymentFrequency" yaml:"deploymentFrequency"`
+	LeadTimeForChanges  DurationThreshold  `json:"leadTimeForChanges" yaml:"leadTimeForChanges"`
+	TimeToRestore       DurationThreshold  `json:"timeToRestore" yaml:"timeToRestore"`
+	ChangeFailureRate   RateThreshold      `json:"changeFailureRate" yaml:"changeFailureRate"`
+}
+
+// DefaultPerformanceThresholds returns thresholds of the bands of Accelerate State of DevOps 2021.
+// Deployment frequency: elite is on-demand (daily), high is weekly to monthly, medium is monthly to once per 6 months.
+// Lead time for changes: elite is less than one hour, high is up to one week, medium is up to 6 months.
+// Time to restore: elite is less than one hour, high is less than one day, medium is up to one week.
+// Change failure rate: the bands overlap in the report, so that they are split by 15%.
+func DefaultPerformanceThresholds() PerformanceThresholds {
+	day := 24 * time.Hour
+	return PerformanceThresholds{
+		DeploymentFrequency: FrequencyThreshold{Elite: 1, High: 1.0 / 30, Medium: 1.0 / 180},
+		LeadTimeForChanges:  DurationThreshold{Elite: time.Hour, High: 7 * day, Medium: 180 * day},
+		TimeToRestore:       DurationThreshold{Elite: time.Hour, High: day, Medium: 7 * day},
+		ChangeFailureRate:   RateThreshold{Elite: 0.15, High: 0.30, Medium: 0.45},
+	}
+}
+
+// ReadPerformanceThresholds reads thresholds from path.
+// The file is YAML if its extension is ".yaml" or ".yml", otherwise JSON.
+// Durations are strings of time.ParseDuration such as "168h". Nanoseconds are also available in JSON.
+// Thresholds not in the file are DefaultPerformanceThresholds.
+func ReadPerformanceThresholds(path string) (PerformanceThresholds, error) {
+	thresholds := DefaultPerformanceThresholds()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return thresholds, err
+	}
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension == ".yaml" || extension == ".yml" {
+		err = yaml.Unmarshal(data, &thresholds)
+	} else {
+		err = json.Unmarshal(data, &thresholds)
+	}
+	return thresholds, err
+}
+
+// Performance is PerformanceTier of each of four keys.
+type Performance struct {
+	DeploymentFrequency PerformanceTier `json:"deploymentFrequency"`
+	LeadTimeForChanges  PerformanceTier `json:"leadTimeForChanges"`
+	TimeToRestore       PerformanceTier `json:"timeToRestore"`
+	ChangeFailureRate   PerformanceTier `json:"changeFailureRate"`
+	// Overall is the lowest tier of four keys except PerformanceTierUnknown
+	Overall PerformanceTier `json:"overall"`
+}
+
+// ClassifyPerformance classifies four keys by thresholds.
+// deploymentFrequency is deployments per day.
+// leadTimeForChanges, timeToRestore and changeFailureRate are nil if there is no data, and they are PerformanceTierUnknown.
+func ClassifyPerformance(deploymentFrequency float64, leadTimeForChanges *time.Duration, timeToRestore *time.Duration, changeFailureRate *float64, thresholds PerformanceThresholds) Performance {
+	performance := Performance{
+		DeploymentFrequency: classifyByMinimum(deploymentFrequency, thresholds.DeploymentFrequency.Elite, thresholds.DeploymentFrequency.High, thresholds.DeploymentFrequency.Medium),
+		LeadTimeForChanges:  PerformanceTierUnknown,
+		TimeToRestore:       PerformanceTierUnknown,
+		ChangeFailureRate:   PerformanceTierUnknown,
+	}
+	if leadTimeForChanges != nil {
+		performance.LeadTimeForChanges = classifyByMaximum(float64(*leadTimeForChanges), float64(thresholds.LeadTimeForChanges.Elite), float64(thresholds.LeadTimeForChanges.High), float64(thresholds.LeadTimeForChanges.Medium))
+	}
+	if timeToRestore != nil {
+		performance.TimeToRestore = classifyByMaximum(float64(*timeToRestore), float64(thresholds.TimeToRestore.Elite), float64(thresholds.TimeToRestore.High), float64(thresholds.TimeToRestore.Medium))
+	}
+	if changeFailureRate != nil {
+		performance.ChangeFailureRate = classifyByMaximum(*changeFailureRate, thresholds.ChangeFailureRate.Elite, thresholds.ChangeFailureRate.High, thresholds.ChangeFailureRate.Medium)
+	}
+	performance.Overall = PerformanceTierElite
+	for _, tier := range []PerformanceTier{performance.DeploymentFrequency, performance.LeadTimeForChanges, performance.TimeToRestore, performance.ChangeFailureRate} {
+		if tier != PerformanceTierUnknown && tier.isLowerThan(performance.Overall) {
+			performance.Overall = tier
+		}
+	}
+	return performance
+}
+
+func (t PerformanceTier) isLowerThan(another PerformanceTier) bool {
+	for _, tier := range performanceTierOrder {
+		if tier == t {
+			return false
+		}
+		if tier == another {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyByMinimum classifies value that is better if it is higher.
+func classifyByMinimum(value float64, elite float64, high float64, medium float64) PerformanceTier {
+	switch {
+	case value >= elite:
+		return PerformanceTierElite
+	case value >= high:
+		return PerformanceTierHigh
+	case value >= medium:
+		return PerformanceTierMedium
+	}
+	return PerformanceTierLow
+}
+
+// classifyByMaximum classifies value that is better if it is lower.
+func classifyByMaximum(value float64, elite float64, high float64, medium float64) PerformanceTier {
+	switch {
+	case value <= elite:
+		return PerformanceTierElite
+	case value <= high:
+		return PerformanceTierHigh
+	case value <= medium:
+		return PerformanceTierMedium
+	}
+	return PerformanceTierLow
+}
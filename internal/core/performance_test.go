@@ -0,0 +1,85 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClassifyPerformanceShouldClassifyEachMetricAndOverall(t *testing.T) {
+	thresholds := DefaultPerformanceThresholds()
+	testCases := []struct {
+		deploymentFrequency float64
+		leadTimeForChanges  time.Duration
+		timeToRestore       time.Duration
+		changeFailureRate   float64
+		expected            Performance
+	}{
+		{2, 30 * time.Minute, 30 * time.Minute, 0.1, Performance{PerformanceTierElite, PerformanceTierElite, PerformanceTierElite, PerformanceTierElite, PerformanceTierElite}},
+		{1.0 / 7, 2 * 24 * time.Hour, 12 * time.Hour, 0.2, Performance{PerformanceTierHigh, PerformanceTierHigh, PerformanceTierHigh, PerformanceTierHigh, PerformanceTierHigh}},
+		{1, 30 * time.Minute, 3 * 24 * time.Hour, 0.1, Performance{PerformanceTierElite, PerformanceTierElite, PerformanceTierMedium, PerformanceTierElite, PerformanceTierMedium}},
+		{0, 365 * 24 * time.Hour, 30 * 24 * time.Hour, 0.5, Performance{PerformanceTierLow, PerformanceTierLow, PerformanceTierLow, PerformanceTierLow, PerformanceTierLow}},
+	}
+	for _, testCase := range testCases {
+		actual := ClassifyPerformance(testCase.deploymentFrequency, &testCase.leadTimeForChanges, &testCase.timeToRestore, &testCase.changeFailureRate, thresholds)
+		if actual != testCase.expected {
+			t.Errorf("performance of %+v should be %+v but %+v", testCase, testCase.expected, actual)
+		}
+	}
+}
+
+func TestClassifyPerformanceShouldBeUnknownWithoutData(t *testing.T) {
+	leadTimeForChanges := 2 * 24 * time.Hour
+	actual := ClassifyPerformance(1.0/7, &leadTimeForChanges, nil, nil, DefaultPerformanceThresholds())
+
+	expected := Performance{PerformanceTierHigh, PerformanceTierHigh, PerformanceTierUnknown, PerformanceTierUnknown, PerformanceTierHigh}
+	if actual != expected {
+		t.Errorf("performance without time to restore and change failure rate should be %+v but %+v", expected, actual)
+	}
+}
+
+func TestReadPerformanceThresholdsShouldOverrideDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.yaml")
+	os.WriteFile(path, []byte(`
+leadTimeForChanges:
+  elite: 24h
+  high: 168h
+  medium: 720h
+changeFailureRate:
+  elite: 0.05
+`), 0644)
+
+	thresholds, err := ReadPerformanceThresholds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultPerformanceThresholds()
+	expected.LeadTimeForChanges = DurationThreshold{Elite: 24 * time.Hour, High: 168 * time.Hour, Medium: 720 * time.Hour}
+	expected.ChangeFailureRate.Elite = 0.05
+	if thresholds != expected {
+		t.Errorf("thresholds should be %+v but %+v", expected, thresholds)
+	}
+}
+
+func TestReadPerformanceThresholdsShouldReadDurationStringsOfJson(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thresholds.json")
+	os.WriteFile(path, []byte(`{"leadTimeForChanges": {"elite": "24h", "high": 604800000000000}, "timeToRestore": {"medium": "72h"}}`), 0644)
+
+	thresholds, err := ReadPerformanceThresholds(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := DefaultPerformanceThresholds()
+	expected.LeadTimeForChanges.Elite = 24 * time.Hour
+	expected.LeadTimeForChanges.High = 168 * time.Hour
+	expected.TimeToRestore.Medium = 72 * time.Hour
+	if thresholds != expected {
+		t.Errorf("thresholds should be %+v but %+v", expected, thresholds)
+	}
+
+	os.WriteFile(path, []byte(`{"leadTimeForChanges": {"elite": "one day"}}`), 0644)
+	if _, err := ReadPerformanceThresholds(path); err == nil {
+		t.Errorf("invalid duration should be error")
+	}
+}
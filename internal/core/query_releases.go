@@ -1,13 +1,9 @@
 package core
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
-	"os/exec"
-	"regexp"
-	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,8 +19,8 @@ func ignoreReleases(sources []ReleaseSource, option *Option) []ReleaseSource {
 	}
 	filteredSources := make([]ReleaseSource, 0)
 	for _, source := range sources {
-		if option.shouldIgnore(source.tag.Name().Short()) {
-			option.Debugln(source.tag.Name().Short(), " is ignored")
+		if option.shouldIgnore(source.name) {
+			option.Debugln(source.name, " is ignored")
 			continue
 		}
 		filteredSources = append(filteredSources, source)
@@ -32,61 +28,164 @@ func ignoreReleases(sources []ReleaseSource, option *Option) []ReleaseSource {
 	return filteredSources
 }
 
+// collapsePrereleases removes pre-release sources if option.Prereleases is PrereleaseModeCollapse,
+// so that a pre-release is never the previous release and its commits are shipped by the subsequent final release.
+func collapsePrereleases(sources []ReleaseSource, option *Option) []ReleaseSource {
+	if option == nil || option.Prereleases != PrereleaseModeCollapse {
+		return sources
+	}
+	collapsedSources := make([]ReleaseSource, 0)
+	for _, source := range sources {
+		if isPrereleaseName(option.versionOf(source.name)) {
+			option.Debugln(source.name, "is collapsed into the subsequent release")
+			continue
+		}
+		collapsedSources = append(collapsedSources, source)
+	}
+	return collapsedSources
+}
+
 func createReleasesBySources(sources []ReleaseSource, option *Option, repository *git.Repository) []*Release {
-	releasesChannel := make(chan *Release)
+	// releasesBySources keeps the order of sources
+	releasesBySources := make([]*Release, len(sources))
+	var waitGroup sync.WaitGroup
 	// go-git repository is not thread-safe, so we need to protect it with a mutex
 	var repositoryMutex sync.Mutex
+	usesLocalGit := option != nil && option.IsLocalRepository
+	gitDirectory := ""
+	if usesLocalGit {
+		var err error
+		if gitDirectory, err = getGitDirectory(repository); err != nil {
+			option.Debugln("Cannot use local git:", err, ". go-git is used instead")
+			usesLocalGit = false
+		}
+	}
 	createReleaseOrNil := func(source ReleaseSource, i int) {
-		if !option.isInTimeRange(source.commit.Committer.When) {
-			option.Debugln("source[", i, "](", source.tag.Name().Short(), ") is skipped for outof time range")
-			releasesChannel <- nil
+		if !option.isInTimeRange(source.date) {
+			option.Debugln("source[", i, "](", source.name, ") is skipped for outof time range")
+			return
+		}
+		if option.excludesPrerelease(source.name) {
+			// the pre-release is still the previous release of the next release, so that commits shipped by it are not counted
+			option.Debugln("source[", i, "](", source.name, ") is skipped for pre-release")
 			return
 		}
 
-		timerEachReleases := fmt.Sprintf("source[%v](%v)GetEachReleases", i, source.tag.Name().Short())
+		timerEachReleases := fmt.Sprintf("source[%v](%v)GetEachReleases", i, source.name)
 		option.StartTimer(timerEachReleases)
 
-		leadTimeForChanges := time.Duration(0)
-		isRestored := false
-		if option != nil && option.IsLocalRepository {
-			isRestored, leadTimeForChanges = getIsRestoredAndLeadTimeForChangesByLocalGit(sources, i, option)
+		var commits []*Commit
+		var stat diffStat
+		cache := option.releaseCache()
+		cacheKey := releaseCacheKey(sources, i, option.paths())
+		countsChangedLines := option != nil && option.CountsChangedLines
+		if entry, ok := cache.get(cacheKey); ok && (entry.HasChangedLines || !countsChangedLines) {
+			option.Debugln("source[", i, "](", source.name, ") is found in cache")
+			commits = entry.Commits
+			if countsChangedLines {
+				stat = diffStat{filesChanged: entry.FilesChanged, linesAdded: entry.LinesAdded, linesDeleted: entry.LinesDeleted}
+			}
 		} else {
-			repositoryMutex.Lock()
-			isRestored, leadTimeForChanges = getIsRestoredAndLeadTimeForChangesByGoGit(sources, i, option, repository)
-			repositoryMutex.Unlock()
+			var err error
+			if usesLocalGit {
+				commits, err = getCommitsByLocalGit(sources, i, gitDirectory, option.paths())
+				if err == nil && countsChangedLines {
+					stat, err = getDiffStatByLocalGit(sources, i, gitDirectory, option.paths())
+				}
+				if err != nil {
+					option.Debugln("source[", i, "](", source.name, ") cannot be read by local git:", err, ". go-git is used instead")
+				}
+			}
+			if !usesLocalGit || err != nil {
+				repositoryMutex.Lock()
+				commits, err = getCommitsByGoGit(sources, i, repository, option.paths())
+				if err == nil && countsChangedLines {
+					stat, err = getDiffStatByGoGit(sources, i, option.paths())
+				}
+				repositoryMutex.Unlock()
+			}
+			if err != nil {
+				// the release has no commits, and it is not cached so that history is walked again in the next query
+				option.Debugln("source[", i, "](", source.name, ") cannot be read:", err)
+				commits = make([]*Commit, 0)
+				stat = diffStat{}
+			} else {
+				cache.put(cacheKey, releaseCacheEntry{Commits: commits, HasChangedLines: countsChangedLines, FilesChanged: stat.filesChanged, LinesAdded: stat.linesAdded, LinesDeleted: stat.linesDeleted})
+			}
 		}
 		option.StopTimer(timerEachReleases)
 
-		releasesChannel <- &Release{
-			Tag:                source.tag.Name().Short(),
-			Date:               source.commit.Committer.When,
+		leadTimeForChanges := time.Duration(0)
+		if oldestCommit := getOldestCommit(commits); oldestCommit != nil {
+			leadTimeForChanges = source.date.Sub(oldestCommit.CommitterWhen)
+		}
+
+		releasesBySources[i] = &Release{
+			Tag:                source.name,
+			Date:               source.date,
 			LeadTimeForChanges: leadTimeForChanges,
 			Result: ReleaseResult{
 				IsSuccess: false,
 			},
-			isRestored: isRestored,
+			BatchSize: newBatchSize(commits, stat),
+			commits:   commits,
+			hash:      source.commit.Hash.String(),
+			isInitial: i == len(sources)-1,
 		}
 	}
 	for i, source := range sources {
-		go createReleaseOrNil(source, i)
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			createReleaseOrNil(source, i)
+		}()
 	}
+	waitGroup.Wait()
 
 	releases := make([]*Release, 0)
-	for range sources {
-		release := <-releasesChannel
+	for _, release := range releasesBySources {
 		if release != nil {
 			releases = append(releases, release)
 		}
 	}
-	sort.Slice(releases, func(i int, j int) bool {
-		return releases[i].Date.After(releases[j].Date)
-	})
-
 	return releases
 }
 
 func setReleaseResultForEachRelease(releases []*Release, option *Option) {
-	var nextSuccessRelease *Release
+	for _, release := range releases {
+		release.Result = ReleaseResult{IsSuccess: true}
+		release.IsRework = false
+		release.restorations = nil
+	}
+	// rework is detected by failure detectors regardless of option.FailureSource, because incidents do not tell unplanned fixes
+	setReleaseReworkByFailureDetectors(releases, option)
+	if option.detectsFailureByFixCommits() {
+		setReleaseResultByFailureDetectors(releases, option)
+	}
+	if option.detectsFailureByIncidents() {
+		setReleaseResultByIncidents(releases, option)
+	}
+}
+
+// setReleaseReworkByFailureDetectors marks each release as rework if any of option.FailureDetectors regards it as an unplanned fix.
+func setReleaseReworkByFailureDetectors(releases []*Release, option *Option) {
+	detectors := option.failureDetectors()
+	for _, release := range releases {
+		if !option.isInTimeRange(release.Date) {
+			continue
+		}
+		for _, detector := range detectors {
+			if detector.IsRework(release) {
+				release.IsRework = true
+			}
+		}
+	}
+}
+
+// setReleaseResultByFailureDetectors marks each release as failure if any of option.FailureDetectors detects it.
+// TimeToRestore is attributed to the restoring release. The failure is not restored if the restoring release is dated before it.
+func setReleaseResultByFailureDetectors(releases []*Release, option *Option) {
+	detectors := option.failureDetectors()
 	for i, release := range releases {
 		if !option.isInTimeRange(release.Date) {
 			option.Debugln("release[", i, "](", release.Tag, ") is skipped for outof time range")
@@ -96,117 +195,228 @@ func setReleaseResultForEachRelease(releases []*Release, option *Option) {
 		timerKeyReleaseMetrics := fmt.Sprintf("release[%v](%v)GetReleaseMetrics", i, release.Tag)
 		option.StartTimer(timerKeyReleaseMetrics)
 
-		isSuccess := true
-		var nextRelease *Release
-		if i > 0 {
-			nextRelease = releases[i-1]
-			isSuccess = !nextRelease.isRestored
-		}
-		release.Result.IsSuccess = isSuccess
-		if isSuccess {
-			if nextRelease != nil && !nextRelease.Result.IsSuccess {
-				timeToRestore := nextSuccessRelease.Date.Sub(releases[i-1].Date)
-				nextSuccessRelease.Result.TimeToRestore = &timeToRestore
+		for _, detector := range detectors {
+			isFailure, restoringRelease := detector.DetectFailure(releases, i)
+			if !isFailure {
+				continue
+			}
+			release.Result.IsSuccess = false
+			if restoringRelease == nil || restoringRelease.restores(release) {
+				continue
 			}
-			nextSuccessRelease = release
+			if restoringRelease.Date.Before(release.Date) {
+				// with ReleaseOrderSemver, the next release by precedence can be deployed before the failed release (e.g. backport)
+				option.Debugln("restoration of", release.Tag, "by", restoringRelease.Tag, "is skipped for restoring release before failure")
+				continue
+			}
+			timeToRestore := restoringRelease.Date.Sub(release.Date)
+			restoringRelease.Result.TimeToRestore = &timeToRestore
+			restoringRelease.restorations = append(restoringRelease.restorations, Restoration{
+				FailedRelease:    release,
+				RestoringRelease: restoringRelease,
+				TimeToRestore:    timeToRestore,
+				Start:            release.Date,
+				End:              restoringRelease.Date,
+			})
 		}
 
 		option.StopTimer(timerKeyReleaseMetrics)
 	}
 }
 
-// QueryReleases returns Releases sorted by date (first item is the oldest and last item is the newest)
-func QueryReleases(repository *git.Repository, option *Option) []*Release {
-	option.StartTimer("QueryReleases")
-	defer option.StopTimer("QueryReleases")
+// setReleaseResultByIncidents marks each release that caused an incident of option.Incidents as failure.
+// Incidents of other severities than option.IncidentSeverities are ignored.
+// TimeToRestore is from the start to the resolution of each incident. There is no restoring release,
+// so that it is not set to Result.TimeToRestore but the restoration is attributed to the failed release.
+func setReleaseResultByIncidents(releases []*Release, option *Option) {
+	for _, incident := range option.Incidents {
+		if !option.includesIncidentSeverity(incident.Severity) {
+			option.Debugln("incident of", incident.Release, "is skipped for severity", incident.Severity)
+			continue
+		}
+		release := findReleaseOfIncident(releases, incident)
+		if release == nil {
+			option.Debugln("incident of", incident.Release, "is skipped for release not found")
+			continue
+		}
+		release.Result.IsSuccess = false
+		release.restorations = append(release.restorations, Restoration{
+			FailedRelease: release,
+			TimeToRestore: incident.Resolved.Sub(incident.Start),
+			Start:         incident.Start,
+			End:           incident.Resolved,
+		})
+	}
+}
+
+// findReleaseOfIncident returns the newest release of incident.Release deployed before the incident started.
+// If all of them are deployed after the incident started, it returns the newest one.
+func findReleaseOfIncident(releases []*Release, incident Incident) *Release {
+	var found *Release
+	for _, release := range releases {
+		if !release.isReleaseOf(incident.Release) {
+			continue
+		}
+		if !release.Date.After(incident.Start) {
+			return release
+		}
+		if found == nil {
+			found = release
+		}
+	}
+	return found
+}
+
+// queryReleaseSources returns ReleaseSources specified by option.ReleaseSource.
+func queryReleaseSources(repository *git.Repository, option *Option) []ReleaseSource {
+	if option != nil && option.ReleaseSource == ReleaseSourceDeployment {
+		return getReleaseSourcesFromDeployments(repository, option)
+	}
+	if option != nil && option.ReleaseSource == ReleaseSourceBranch {
+		option.StartTimer("QueryMergeCommits")
+		defer option.StopTimer("QueryMergeCommits")
+		sources, err := getReleaseSourcesFromBranch(repository, option.ReleaseBranch)
+		if err != nil {
+			option.Debugln("Cannot get merge commits of", option.ReleaseBranch, ":", err)
+		}
+		return sources
+	}
 	option.StartTimer("QueryTags")
 	tags := QueryTags(repository)
 	option.StopTimer("QueryTags")
 	option.Debugln("Tags count:", len(tags))
-	sources := getReleaseSourcesFromTags(repository, tags)
+	sources := make([]ReleaseSource, 0)
+	for _, source := range getReleaseSourcesFromTags(repository, tags) {
+		if !option.includesTag(source.name) {
+			option.Debugln(source.name, "is excluded for not matching tag pattern")
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if option != nil && option.ReleaseOrder == ReleaseOrderSemver {
+		return sortReleaseSourcesBySemanticVersion(sources, option)
+	}
+	return sources
+}
+
+// QueryReleases returns Releases sorted by date, or by semantic version if option.ReleaseOrder is ReleaseOrderSemver (first item is the newest)
+// If option.ComponentPattern is specified, releases of all components of QueryComponentReleases are returned.
+func QueryReleases(repository *git.Repository, option *Option) []*Release {
+	if option != nil && option.ComponentPattern != nil {
+		return AggregateComponentReleases(QueryComponentReleases(repository, option))
+	}
+	option.StartTimer("QueryReleases")
+	defer option.StopTimer("QueryReleases")
+	sources := queryReleaseSources(repository, option)
 	sources = ignoreReleases(sources, option)
 	option.Debugln("Sources count:", len(sources))
 
+	return queryReleasesOfSources(sources, option, repository)
+}
+
+// queryReleasesOfSources returns Releases of sources with their results.
+func queryReleasesOfSources(sources []ReleaseSource, option *Option, repository *git.Repository) []*Release {
+	sources = collapsePrereleases(sources, option)
 	releases := createReleasesBySources(sources, option, repository)
+	if option != nil && option.Location != nil {
+		for _, release := range releases {
+			release.Date = release.Date.In(option.Location)
+		}
+	}
 	setReleaseResultForEachRelease(releases, option)
+	setOutliers(releases, option)
 	return releases
 }
 
-// getIsRestoredAndLeadTimeForChangesByLocalGit gets isRestored and leadTimeForChanges by using local git command.
+// getCommitsByLocalGit gets commits of sources[i] which are not included in the previous release by using local git command.
 // Local git command is about 10 times faster than go-git.
-func getIsRestoredAndLeadTimeForChangesByLocalGit(
-	sources []ReleaseSource,
-	i int,
-	option *Option,
-) (isRestored bool, leadTimeForChanges time.Duration) {
+// The command runs for gitDirectory, so that it works for bare repositories too.
+// If paths are specified, only commits that touch the paths are returned.
+func getCommitsByLocalGit(sources []ReleaseSource, i int, gitDirectory string, paths []string) ([]*Commit, error) {
 	source := sources[i]
-	// Epoch time
-	since := "1970-01-01T00:00:00+0000"
+	// commits reachable from this release but not from the previous release
+	revisionRange := source.commit.Hash.String()
 	if i < len(sources)-1 {
 		preReleaseCommit := sources[i+1].commit
-		since = preReleaseCommit.Committer.When.Add(time.Second).Format("2006-01-02T15:04:05")
+		revisionRange = preReleaseCommit.Hash.String() + ".." + revisionRange
 	}
-	restoresPreRelease := false
-	output, cmdErr := exec.Command("git", "log",
-		"--since", since,
-		`--format="%ct %s"`,
+	// each commit is formatted as "<hash>\n<author name>\n<author email>\n<author unixtime>\n<committer unixtime>\n<message>" and separated by NUL
+	args := []string{"log",
+		"-z",
+		"--format=%H%n%an%n%ae%n%at%n%ct%n%B",
 		"--date-order",
-		source.commit.Hash.String(),
-	).Output()
-	lastLine := ""
-	if cmdErr == nil {
-		scanner := bufio.NewScanner(bytes.NewReader(output))
-		scanner.Split(bufio.ScanLines)
-		for scanner.Scan() {
-			line := scanner.Text()
-			if option.isFixedCommit(line) {
-				restoresPreRelease = true
-			}
-			lastLine = line
+		revisionRange,
+	}
+	if len(paths) > 0 {
+		// --full-history keeps the same commits as touchesPaths without history simplification
+		args = append(args, "--full-history", "--")
+		for _, path := range paths {
+			args = append(args, ":(glob)"+path)
 		}
 	}
-	isRestored = restoresPreRelease
-	leadTimeForChanges = time.Duration(0)
-	pattern, _ := regexp.Compile(`^[^\d]+(\d+) `)
-	if cmdErr == nil && lastLine != "" {
-		matches := pattern.FindStringSubmatch(lastLine)
-		unixtimeString := matches[1]
-		unixtimeInt, err := strconv.ParseInt(unixtimeString, 10, 64)
-		if err == nil {
-			lastCommitWhen := time.Unix(unixtimeInt, 0)
-			leadTimeForChanges = source.commit.Committer.When.Sub(lastCommitWhen)
+	output, err := runLocalGit(gitDirectory, args...)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]*Commit, 0)
+	for _, record := range strings.Split(string(output), "\x00") {
+		fields := strings.SplitN(record, "\n", 6)
+		if len(fields) < 6 {
+			continue
+		}
+		authorUnixtimeInt, err := strconv.ParseInt(fields[3], 10, 64)
+		if err != nil {
+			continue
+		}
+		unixtimeInt, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			continue
 		}
+		commits = append(commits, &Commit{
+			Hash:          fields[0],
+			Message:       fields[5],
+			AuthorName:    fields[1],
+			AuthorEmail:   fields[2],
+			AuthorWhen:    time.Unix(authorUnixtimeInt, 0),
+			CommitterWhen: time.Unix(unixtimeInt, 0),
+		})
 	}
-	return isRestored, leadTimeForChanges
+	return commits, nil
 }
 
-// getIsRestoredAndLeadTimeForChangesByGoGit gets isRestored and leadTimeForChanges by using go-git.
+// getCommitsByGoGit gets commits of sources[i] which are not included in the previous release by using go-git.
 // go-git is slow but it can use in-memory repository.
 // When repository is specified by url, repository is in-memory so that go-git is used.
-func getIsRestoredAndLeadTimeForChangesByGoGit(
-	sources []ReleaseSource,
-	i int,
-	option *Option,
-	repository *git.Repository,
-) (isRestored bool, leadTimeForChanges time.Duration) {
+// If paths are specified, only commits that touch the paths are returned.
+func getCommitsByGoGit(sources []ReleaseSource, i int, repository *git.Repository, paths []string) ([]*Commit, error) {
 	source := sources[i]
-	var lastCommit *object.Commit
 	var preReleaseCommit *object.Commit
 	if i < len(sources)-1 {
 		preReleaseCommit = sources[i+1].commit
 	}
-	restoresPreRelease := false
+	commits := make([]*Commit, 0)
 	err := traverseCommits(repository, preReleaseCommit, source.commit, func(c *object.Commit) error {
-		if option.isFixedCommit(c.Message) {
-			restoresPreRelease = true
+		if len(paths) > 0 {
+			touches, err := touchesPaths(c, paths)
+			if err != nil {
+				return err
+			}
+			if !touches {
+				return nil
+			}
 		}
-		lastCommit = c
+		commits = append(commits, &Commit{
+			Hash:          c.Hash.String(),
+			Message:       c.Message,
+			AuthorName:    c.Author.Name,
+			AuthorEmail:   c.Author.Email,
+			AuthorWhen:    c.Author.When,
+			CommitterWhen: c.Committer.When,
+		})
 		return nil
 	})
-	isRestored = restoresPreRelease
-	leadTimeForChanges = time.Duration(0)
-	if err == nil && lastCommit != nil {
-		leadTimeForChanges = source.commit.Committer.When.Sub(lastCommit.Committer.When)
+	if err != nil {
+		return nil, err
 	}
-	return isRestored, leadTimeForChanges
+	return commits, nil
 }
@@ -1,25 +1,36 @@
 package core
 
 import (
+	"fmt"
 	"os"
 	"regexp"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/hmiyado/four-keys/internal/util"
 )
 
-var repository, repositoryCli, emptyRepository *git.Repository
+var repository, repositoryAnnotatedTags, emptyRepository *git.Repository
 
 func TestMain(m *testing.M) {
-	repository, _ = git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-		URL: "https://github.com/go-git/go-git",
-	})
-	repositoryCli, _ = git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
-		URL: "https://github.com/urfave/cli",
-	})
+	var err error
+	repository, err = util.NewRepositoryFromGitGraph(util.ExampleGitGraph)
+	if err != nil {
+		panic(err)
+	}
+	repositoryAnnotatedTags, err = util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "c1" annotatedTag: "v1"
+  commit id: "c2" annotatedTag: "v2"
+  commit id: "c3" tag: "v3"
+`)
+	if err != nil {
+		panic(err)
+	}
 	emptyRepository, _ = git.Init(memory.NewStorage(), nil)
 
 	code := m.Run()
@@ -77,95 +88,168 @@ func TestQueryReleasesShouldReturnEmptyForEmptyRepository(t *testing.T) {
 
 func TestQueryReleasesShouldReturnReleasesWithSpecifiedTimeRange(t *testing.T) {
 	releases := QueryReleases(repository, &Option{
-		Since: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-		Until: time.Date(2020, 12, 31, 23, 59, 59, 999, time.UTC),
+		Since: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2023, 4, 14, 23, 59, 59, 999, time.UTC),
 	})
-	tag5_2_0 := &Release{Tag: "v5.2.0", Date: time.Date(2020, 10, 9, 11, 49, 30, 0, time.FixedZone("+0200", 2*60*60)), LeadTimeForChanges: time.Duration(11299320000000000), Result: ReleaseResult{IsSuccess: true}}
-	tag5_1_0 := &Release{Tag: "v5.1.0", Date: time.Date(2020, 5, 24, 19, 25, 8, 0, time.FixedZone("+0200", 2*60*60)), LeadTimeForChanges: time.Duration(6036349000000000), Result: ReleaseResult{IsSuccess: true}}
-	tag5_0_0 := &Release{Tag: "v5.0.0", Date: time.Date(2020, 3, 15, 21, 18, 32, 0, time.FixedZone("+0100", 1*60*60)), LeadTimeForChanges: time.Duration(19417077000000000), Result: ReleaseResult{IsSuccess: true}}
-	expectedTags := []*Release{tag5_2_0, tag5_1_0, tag5_0_0}
+	tag1_0_0 := &Release{Tag: "v1.0.0", Date: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: time.Duration(0), Result: ReleaseResult{IsSuccess: true}}
+	tag0_1_0 := &Release{Tag: "v0.1.0", Date: time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC), LeadTimeForChanges: parseDurationOrZero("216h"), Result: ReleaseResult{IsSuccess: true}}
+	expectedTags := []*Release{tag1_0_0, tag0_1_0}
 
 	assertReleasesAreEqual(t, expectedTags, releases)
 }
 
 func TestQueryReleasesShouldHaveReleaseResult(t *testing.T) {
 	releases := QueryReleases(repository, &Option{
-		Since: time.Date(2015, 12, 20, 0, 0, 0, 0, time.UTC),
-		Until: time.Date(2016, 1, 11, 23, 59, 59, 999, time.UTC),
+		Since: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2023, 4, 30, 23, 59, 59, 999, time.UTC),
 	})
-	tag2_1_2 := &Release{
-		Tag:                "v2.1.2",
-		Date:               time.Date(2016, 1, 11, 12, 9, 15, 0, time.FixedZone("+0100", 1*60*60)),
-		LeadTimeForChanges: parseDurationOrZero("25m24s"),
+	tag2_0_1 := &Release{
+		Tag:                "v2.0.1",
+		Date:               time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: parseDurationOrZero("120h"),
 		Result: ReleaseResult{
 			IsSuccess:     true,
-			TimeToRestore: parseDurationOrNil("67h7m39s"),
+			TimeToRestore: parseDurationOrNil("360h"),
 		},
 	}
-	tag2_1_1 := &Release{
-		Tag:                "v2.1.1",
-		Date:               time.Date(2016, 1, 8, 17, 1, 36, 0, time.FixedZone("+0100", 1*60*60)),
-		LeadTimeForChanges: parseDurationOrZero("12m26s"),
+	tag2_0_0 := &Release{
+		Tag:                "v2.0.0",
+		Date:               time.Date(2023, 4, 15, 0, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: parseDurationOrZero("240h"),
 		Result: ReleaseResult{
 			IsSuccess:     false,
 			TimeToRestore: nil,
 		},
 	}
-	tag2_1_0 := &Release{
-		Tag:                "v2.1.0",
-		Date:               time.Date(2015, 12, 23, 9, 48, 11, 0, time.FixedZone("+0100", 1*60*60)),
-		LeadTimeForChanges: time.Duration(894609000000000),
+	tag1_0_0 := &Release{
+		Tag:                "v1.0.0",
+		Date:               time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: time.Duration(0),
 		Result: ReleaseResult{
 			IsSuccess:     true,
 			TimeToRestore: nil,
 		},
 	}
-	expectedTags := []*Release{tag2_1_2, tag2_1_1, tag2_1_0}
+	expectedTags := []*Release{tag2_0_1, tag2_0_0, tag1_0_0}
 
 	assertReleasesAreEqual(t, expectedTags, releases)
 }
 
 func TestQueryReleasesShouldReturnReleasesWithIgnorePattern(t *testing.T) {
-	pattern, _ := regexp.Compile(`v5\.0\.0|v5\.2\.0`)
+	pattern, _ := regexp.Compile(`v2\.0\.0|v1\.0\.0`)
 	releases := QueryReleases(repository, &Option{
-		Since:         time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-		Until:         time.Date(2020, 12, 31, 23, 59, 59, 999, time.UTC),
+		Since:         time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 4, 30, 23, 59, 59, 999, time.UTC),
 		IgnorePattern: pattern,
 	})
-	tag5_1_0 := &Release{
-		Tag:                "v5.1.0",
-		Date:               time.Date(2020, 5, 24, 19, 25, 8, 0, time.FixedZone("+0200", 2*60*60)),
-		LeadTimeForChanges: time.Duration(25454673000000000),
+	tag2_0_1 := &Release{
+		Tag:                "v2.0.1",
+		Date:               time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: parseDurationOrZero("696h"),
 		Result: ReleaseResult{
 			IsSuccess:     true,
 			TimeToRestore: nil,
 		},
 	}
-	expectedTags := []*Release{tag5_1_0}
+	expectedTags := []*Release{tag2_0_1}
 
 	assertReleasesAreEqual(t, expectedTags, releases)
 }
 
 func TestQueryReleasesShouldReturnSameReleasesRepositoryIsLocalOrNot(t *testing.T) {
-	since, _ := time.Parse("2006-01-02", "2022-01-01")
-	until := time.Now()
-	ignorePattern := regexp.MustCompile(`v[^1].[^2].[^0]|v1.[^2].[^0]|v1.2.[^0]|v1.[^2].0`)
-	fourKeysRepository, _ := git.PlainOpenWithOptions("./", &git.PlainOpenOptions{DetectDotGit: true, EnableDotGitCommonDir: false})
-	releasesOfLocalRepository := QueryReleases(fourKeysRepository, &Option{
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).GitGraph(util.ExampleGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	releasesOfLocalRepository := QueryReleases(localRepository, &Option{
 		Since:             since,
 		Until:             until,
-		IgnorePattern:     ignorePattern,
 		IsLocalRepository: true,
 	})
-	releasesOfNotLocalRepository := QueryReleases(fourKeysRepository, &Option{
+	releasesOfNotLocalRepository := QueryReleases(localRepository, &Option{
 		Since:             since,
 		Until:             until,
-		IgnorePattern:     ignorePattern,
 		IsLocalRepository: false,
 	})
+	if len(releasesOfLocalRepository) != len(QueryTags(localRepository)) {
+		t.Errorf("releases should have all tags but %v", releasesOfLocalRepository)
+	}
 	assertReleasesAreEqual(t, releasesOfLocalRepository, releasesOfNotLocalRepository)
 }
 
+func TestQueryReleasesShouldUseLocalGitForBareRepository(t *testing.T) {
+	directory := t.TempDir()
+	_, err := util.NewRepositoryBuilderOnDisk(directory).GitGraph(util.ExampleGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bareRepository, err := git.PlainClone(t.TempDir(), true, &git.CloneOptions{URL: directory, Mirror: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	since := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	debugMessages := make([]string, 0)
+	var debugMutex sync.Mutex
+	releasesByLocalGit := QueryReleases(bareRepository, &Option{
+		Since:             since,
+		Until:             until,
+		IsLocalRepository: true,
+		DebuglnFunc: func(a ...any) {
+			debugMutex.Lock()
+			defer debugMutex.Unlock()
+			debugMessages = append(debugMessages, fmt.Sprintln(a...))
+		},
+	})
+	releasesByGoGit := QueryReleases(bareRepository, &Option{
+		Since: since,
+		Until: until,
+	})
+	for _, message := range debugMessages {
+		if strings.Contains(message, "go-git is used instead") {
+			t.Errorf("local git should be used for bare repository but %v", message)
+		}
+	}
+	if len(releasesByLocalGit) != len(QueryTags(bareRepository)) {
+		t.Errorf("releases should have all tags but %v", releasesByLocalGit)
+	}
+	assertReleasesAreEqual(t, releasesByGoGit, releasesByLocalGit)
+}
+
+func TestQueryReleasesShouldHaveAuthorOfCommitsRepositoryIsLocalOrNot(t *testing.T) {
+	authorWhen := time.Date(2023, 4, 1, 9, 0, 0, 0, time.UTC)
+	committerWhen := time.Date(2023, 4, 2, 9, 0, 0, 0, time.UTC)
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).
+		Commit("initial", time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)).
+		Tag("v1").
+		CommitWith(util.CommitOptions{Message: "fix: subject\n\nbody", Author: "alice", AuthorWhen: authorWhen, CommitterWhen: committerWhen}).
+		Tag("v2").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, isLocalRepository := range []bool{true, false} {
+		releases := QueryReleases(localRepository, &Option{
+			Since:             time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Until:             time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			IsLocalRepository: isLocalRepository,
+		})
+		commits := releases[0].Commits()
+		if len(commits) != 1 {
+			t.Fatalf("v2 should have a commit (local: %v) but %v", isLocalRepository, commits)
+		}
+		commit := commits[0]
+		if commit.AuthorName != "alice" || commit.AuthorEmail != "alice@example.com" || !commit.AuthorWhen.Equal(authorWhen) || !commit.CommitterWhen.Equal(committerWhen) {
+			t.Errorf("commit should be authored by alice at %v (local: %v) but %+v", authorWhen, isLocalRepository, commit)
+		}
+		if commit.Subject() != "fix: subject" {
+			t.Errorf("subject should be the first line (local: %v) but %v", isLocalRepository, commit.Subject())
+		}
+	}
+}
+
 func parseDurationOrZero(str string) time.Duration {
 	d, err := time.ParseDuration(str)
 	if err != nil {
@@ -208,3 +292,440 @@ func assertReleasesAreEqual(t *testing.T, releasesExpected []*Release, releasesA
 	}
 	t.Errorf("releases does not have specified")
 }
+
+func TestQueryReleasesShouldReturnMergeCommitsOnReleaseBranch(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "initial" date: "2023-03-01"
+  branch production
+  checkout main
+  commit id: "c0" date: "2023-04-01"
+  checkout production
+  merge main id: "deploy0" date: "2023-04-02"
+  checkout main
+  commit id: "c1" date: "2023-04-03"
+  commit id: "c2" date: "2023-04-05"
+  checkout production
+  merge main id: "deploy1" date: "2023-04-10"
+  commit id: "p1" date: "2023-04-11"
+  checkout main
+  commit id: "c3" msg: "hotfix: c3" date: "2023-04-12"
+  checkout production
+  merge main id: "deploy2" date: "2023-04-13"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deploy2, _ := r.Reference("refs/heads/production", true)
+	releases := QueryReleases(r, &Option{
+		Since:         time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		ReleaseSource: ReleaseSourceBranch,
+		ReleaseBranch: "production",
+	})
+
+	deploy2Release := &Release{
+		Tag:                deploy2.Hash().String()[:7],
+		Date:               time.Date(2023, 4, 13, 0, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: parseDurationOrZero("48h"),
+		Result: ReleaseResult{
+			IsSuccess:     true,
+			TimeToRestore: parseDurationOrNil("72h"),
+		},
+	}
+	if len(releases) != 3 {
+		t.Fatalf("releases should be deploy0, deploy1 and deploy2 but %v", releases)
+	}
+	assertReleasesAreEqual(t, []*Release{deploy2Release}, releases[:1])
+	deploy1Release := releases[1]
+	if !deploy1Release.Date.Equal(time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC)) || deploy1Release.LeadTimeForChanges != parseDurationOrZero("168h") || deploy1Release.Result.IsSuccess {
+		t.Errorf("deploy1 should be failed release at 2023-04-10 with lead time 7 days but %v", deploy1Release)
+	}
+}
+
+func TestQueryReleasesShouldReturnEmptyWithUnknownReleaseBranch(t *testing.T) {
+	releases := QueryReleases(repository, &Option{
+		Since:         time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Until:         time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+		ReleaseSource: ReleaseSourceBranch,
+		ReleaseBranch: "production",
+	})
+
+	if len(releases) != 0 {
+		t.Errorf("releases should be empty but %v", releases)
+	}
+}
+
+func TestQueryReleasesShouldDetectFailureByRevertCommits(t *testing.T) {
+	builder := util.NewRepositoryBuilder().
+		Commit("c1", time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)).Tag("v1").
+		Commit("c2", time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC)).Tag("v2")
+	reverted := builder.Head()
+	builder.
+		Commit("c3", time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC)).Tag("v3").
+		Commit("Revert \"c2\"\n\nThis reverts commit "+reverted.String()+".\n", time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC)).Tag("v4")
+	r, err := builder.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := QueryReleases(r, &Option{
+		Since:            time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:            time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		FailureDetectors: []FailureDetector{RevertCommitDetector{}},
+	})
+
+	assertReleasesAreEqual(t, []*Release{
+		{
+			Tag:                "v4",
+			Date:               time.Date(2023, 4, 5, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("0s"),
+			Result:             ReleaseResult{IsSuccess: true, TimeToRestore: parseDurationOrNil("72h")},
+		},
+		{
+			Tag:                "v3",
+			Date:               time.Date(2023, 4, 3, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("0s"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+		{
+			Tag:                "v2",
+			Date:               time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("0s"),
+			Result:             ReleaseResult{IsSuccess: false},
+		},
+		{
+			Tag:                "v1",
+			Date:               time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("0s"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+	}, releases)
+	restorations := GetRestorations(releases)
+	if len(restorations) != 1 || restorations[0].FailedRelease.Tag != "v2" || restorations[0].RestoringRelease.Tag != "v4" {
+		t.Errorf("v4 should restore v2 but %v", restorations)
+	}
+}
+
+func TestQueryReleasesShouldOrderTagsBySemanticVersion(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "c1" date: "2023-04-01" tag: "v1.0.0"
+  branch release-1.0
+  checkout main
+  commit id: "c2" date: "2023-04-10" tag: "v1.1.0"
+  commit id: "docs" date: "2023-04-12" tag: "docs-2023"
+  checkout release-1.0
+  commit id: "b1" date: "2023-04-15" tag: "v1.0.1"
+  checkout main
+  commit id: "c3" date: "2023-04-20" tag: "v1.2.0"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := QueryReleases(r, &Option{
+		Since:        time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:        time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		TagPattern:   regexp.MustCompile(`^v`),
+		ReleaseOrder: ReleaseOrderSemver,
+	})
+
+	tags := make([]string, 0)
+	for _, release := range releases {
+		tags = append(tags, release.Tag)
+	}
+	if len(tags) != 4 || tags[0] != "v1.2.0" || tags[1] != "v1.1.0" || tags[2] != "v1.0.1" || tags[3] != "v1.0.0" {
+		t.Fatalf("releases should be ordered by semantic version but %v", tags)
+	}
+	// v1.2.0 ships docs and c3 after v1.1.0. the backport v1.0.1 does not change the previous release
+	if releases[0].LeadTimeForChanges != parseDurationOrZero("192h") {
+		t.Errorf("lead time of v1.2.0 should be from docs (2023-04-12) but %v", releases[0].LeadTimeForChanges)
+	}
+}
+
+func TestQueryReleasesShouldNotRestoreByOlderReleaseInSemverOrder(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "c1" date: "2023-03-10" tag: "v1.2.0"
+  branch release-1.2
+  checkout main
+  commit id: "h1" msg: "hotfix: h1" date: "2023-04-01" tag: "v2.0.0"
+  checkout release-1.2
+  commit id: "b1" msg: "backport" date: "2023-04-10" tag: "v1.2.1"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := QueryReleases(r, &Option{
+		Since:        time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:        time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		ReleaseOrder: ReleaseOrderSemver,
+	})
+	if len(releases) != 3 || releases[0].Tag != "v2.0.0" || releases[1].Tag != "v1.2.1" {
+		t.Fatalf("releases should be v2.0.0, v1.2.1 and v1.2.0 but %v", releases)
+	}
+	// v2.0.0 follows the backport v1.2.1 by precedence, but it is deployed before v1.2.1
+	if releases[0].Result.TimeToRestore != nil {
+		t.Errorf("v2.0.0 should not restore v1.2.1 deployed after it but %v", releases[0].Result)
+	}
+	for _, restoration := range GetRestorations(releases) {
+		if restoration.TimeToRestore < 0 {
+			t.Errorf("time to restore should not be negative but %+v", restoration)
+		}
+	}
+	// v1.2.1 is still failure because v2.0.0 ships the hotfix
+	if releases[0].Result.IsSuccess != true || releases[1].Result.IsSuccess != false || releases[2].Result.IsSuccess != true {
+		t.Errorf("only v1.2.1 should be failure but %v", releases)
+	}
+	if statistics := GetTimeToRestoreStatistics(releases); statistics.Count != 0 || statistics.Mean != 0 {
+		t.Errorf("no failure should be restored but %+v", statistics)
+	}
+}
+
+func TestQueryReleasesShouldHandlePrereleases(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(`gitGraph
+  commit id: "c1" date: "2023-03-10" tag: "v1.0.0"
+  commit id: "c2" date: "2023-04-01"
+  commit id: "c3" date: "2023-04-05" tag: "v2.0.0-rc.1"
+  commit id: "c4" date: "2023-04-10" tag: "v2.0.0-rc.2"
+  commit id: "c5" date: "2023-04-15" tag: "v2.0.0"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		mode             PrereleaseMode
+		expectedTags     []string
+		expectedLeadTime time.Duration
+	}{
+		{PrereleaseModeInclude, []string{"v2.0.0", "v2.0.0-rc.2", "v2.0.0-rc.1", "v1.0.0"}, 0},
+		// v2.0.0 ships only c5 because c2 to c4 are shipped by pre-releases
+		{PrereleaseModeExclude, []string{"v2.0.0", "v1.0.0"}, 0},
+		// v2.0.0 ships from c2 to c5
+		{PrereleaseModeCollapse, []string{"v2.0.0", "v1.0.0"}, parseDurationOrZero("336h")},
+	}
+	for _, testCase := range testCases {
+		releases := QueryReleases(r, &Option{
+			Since:       time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			Until:       time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+			Prereleases: testCase.mode,
+		})
+
+		tags := make([]string, 0)
+		for _, release := range releases {
+			tags = append(tags, release.Tag)
+		}
+		if strings.Join(tags, ",") != strings.Join(testCase.expectedTags, ",") {
+			t.Errorf("releases of %v should be %v but %v", testCase.mode, testCase.expectedTags, tags)
+			continue
+		}
+		if releases[0].LeadTimeForChanges != testCase.expectedLeadTime {
+			t.Errorf("lead time of v2.0.0 in %v should be %v but %v", testCase.mode, testCase.expectedLeadTime, releases[0].LeadTimeForChanges)
+		}
+	}
+}
+
+const pathsGitGraph = `gitGraph
+  commit id: "c1" date: "2023-03-01" file: "payments/main.go" tag: "v1"
+  commit id: "c2" date: "2023-04-01" file: "web/index.html"
+  commit id: "c3" date: "2023-04-05" file: "payments/api/handler.go"
+  branch feature
+  commit id: "f1" date: "2023-04-06" file: "payments/feature.go"
+  checkout main
+  commit id: "c4" msg: "hotfix: web" date: "2023-04-08" file: "web/index.html" tag: "v2"
+  merge feature id: "m1" date: "2023-04-09"
+  commit id: "c5" date: "2023-04-10" file: "web/index.html" tag: "v3"
+`
+
+func TestQueryReleasesShouldUseOnlyCommitsTouchingPaths(t *testing.T) {
+	r, err := util.NewRepositoryFromGitGraph(pathsGitGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releases := QueryReleases(r, &Option{
+		Since: time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		Until: time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+		Paths: []string{"payments/**"},
+	})
+
+	assertReleasesAreEqual(t, []*Release{
+		{
+			// f1 and m1 touch payments
+			Tag:                "v3",
+			Date:               time.Date(2023, 4, 10, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("96h"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+		{
+			// c3 touches payments, and the hotfix of web does not make v1 failure
+			Tag:                "v2",
+			Date:               time.Date(2023, 4, 8, 0, 0, 0, 0, time.UTC),
+			LeadTimeForChanges: parseDurationOrZero("72h"),
+			Result:             ReleaseResult{IsSuccess: true},
+		},
+	}, releases)
+}
+
+func TestQueryReleasesShouldReturnSameReleasesWithPathsRepositoryIsLocalOrNot(t *testing.T) {
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).GitGraph(pathsGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, paths := range [][]string{{"payments/**"}, {"payments"}, {"*/main.go"}, {"**/*.go"}, {"web/*", "payments/api"}} {
+		option := &Option{
+			Since: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC),
+			Paths: paths,
+		}
+		releasesOfNotLocalRepository := QueryReleases(localRepository, option)
+		option.IsLocalRepository = true
+		releasesOfLocalRepository := QueryReleases(localRepository, option)
+		t.Logf("paths: %v", paths)
+		assertReleasesAreEqual(t, releasesOfLocalRepository, releasesOfNotLocalRepository)
+	}
+}
+
+func TestQueryReleasesShouldHaveBatchSizeRepositoryIsLocalOrNot(t *testing.T) {
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).GitGraph(pathsGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		paths    []string
+		expected []BatchSize
+	}{
+		{
+			paths: nil,
+			expected: []BatchSize{
+				// m1 takes web/index.html of feature, so that the line of c4 is replaced by c5
+				{Commits: 3, Authors: 1, FilesChanged: 2, LinesAdded: 2, LinesDeleted: 1},
+				{Commits: 3, Authors: 1, FilesChanged: 2, LinesAdded: 3},
+				{Commits: 1, Authors: 1, FilesChanged: 1, LinesAdded: 1},
+			},
+		},
+		{
+			paths: []string{"payments/**"},
+			expected: []BatchSize{
+				{Commits: 2, Authors: 1, FilesChanged: 1, LinesAdded: 1},
+				{Commits: 1, Authors: 1, FilesChanged: 1, LinesAdded: 1},
+				{Commits: 1, Authors: 1, FilesChanged: 1, LinesAdded: 1},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		for _, isLocalRepository := range []bool{true, false} {
+			releases := QueryReleases(localRepository, &Option{
+				Since:              time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				Until:              time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+				Paths:              testCase.paths,
+				IsLocalRepository:  isLocalRepository,
+				CountsChangedLines: true,
+			})
+			if len(releases) != len(testCase.expected) {
+				t.Fatalf("releases should be v3, v2 and v1 but %v", releases)
+			}
+			for i, release := range releases {
+				if release.BatchSize != testCase.expected[i] {
+					t.Errorf("batch size of %v (paths: %v, local: %v) should be %+v but %+v", release.Tag, testCase.paths, isLocalRepository, testCase.expected[i], release.BatchSize)
+				}
+			}
+		}
+	}
+}
+
+func TestQueryReleasesShouldHaveSameChangedLinesRepositoryIsLocalOrNot(t *testing.T) {
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).
+		CommitWith(util.CommitOptions{Message: "c1", CommitterWhen: time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), Contents: map[string]string{"a.txt": "a\nb\nc\n"}}).
+		CommitWith(util.CommitOptions{Message: "c2", CommitterWhen: time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC), Files: []string{"b.txt"}}).
+		CommitWith(util.CommitOptions{Message: "c3", CommitterWhen: time.Date(2023, 3, 3, 0, 0, 0, 0, time.UTC), Files: []string{"b.txt"}}).
+		Tag("v1").
+		// line "a" is moved to the end
+		CommitWith(util.CommitOptions{Message: "c4", CommitterWhen: time.Date(2023, 3, 10, 0, 0, 0, 0, time.UTC), Contents: map[string]string{"a.txt": "b\nc\na\n"}}).
+		Tag("v2").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BatchSize{
+		{Commits: 1, Authors: 1, FilesChanged: 1, LinesAdded: 1, LinesDeleted: 1},
+		// the initial release is compared with the empty tree instead of its parent
+		{Commits: 3, Authors: 1, FilesChanged: 2, LinesAdded: 5},
+	}
+	for _, isLocalRepository := range []bool{true, false} {
+		releases := QueryReleases(localRepository, &Option{
+			Since:              time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Until:              time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+			IsLocalRepository:  isLocalRepository,
+			CountsChangedLines: true,
+		})
+		if len(releases) != len(expected) {
+			t.Fatalf("releases should be v2 and v1 but %v", releases)
+		}
+		for i, release := range releases {
+			if release.BatchSize != expected[i] {
+				t.Errorf("batch size of %v (local: %v) should be %+v but %+v", release.Tag, isLocalRepository, expected[i], release.BatchSize)
+			}
+		}
+	}
+}
+
+func TestQueryReleasesShouldNotCountChangedLinesByDefault(t *testing.T) {
+	localRepository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).GitGraph(pathsGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []BatchSize{{Commits: 3, Authors: 1}, {Commits: 3, Authors: 1}, {Commits: 1, Authors: 1}}
+	for _, isLocalRepository := range []bool{true, false} {
+		releases := QueryReleases(localRepository, &Option{
+			Since:             time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			Until:             time.Date(2023, 4, 30, 0, 0, 0, 0, time.UTC),
+			IsLocalRepository: isLocalRepository,
+		})
+		if len(releases) != len(expected) {
+			t.Fatalf("releases should be v3, v2 and v1 but %v", releases)
+		}
+		for i, release := range releases {
+			if release.BatchSize != expected[i] {
+				t.Errorf("batch size of %v (local: %v) should be %+v but %+v", release.Tag, isLocalRepository, expected[i], release.BatchSize)
+			}
+		}
+	}
+}
+
+func TestGetBatchSizeStatisticsShouldReturnMeanAndMedian(t *testing.T) {
+	releases := []*Release{
+		{BatchSize: BatchSize{Commits: 1, Authors: 1, FilesChanged: 1, LinesAdded: 10, LinesDeleted: 0}},
+		{BatchSize: BatchSize{Commits: 2, Authors: 1, FilesChanged: 3, LinesAdded: 20, LinesDeleted: 5}},
+		{BatchSize: BatchSize{Commits: 9, Authors: 4, FilesChanged: 8, LinesAdded: 300, LinesDeleted: 40}},
+	}
+
+	actual := GetBatchSizeStatistics(releases)
+
+	expected := BatchSizeStatistics{
+		Mean:   BatchSizeStatistic{Commits: 4, Authors: 2, FilesChanged: 4, LinesAdded: 110, LinesDeleted: 15},
+		Median: BatchSizeStatistic{Commits: 2, Authors: 1, FilesChanged: 3, LinesAdded: 20, LinesDeleted: 5},
+	}
+	if actual != expected {
+		t.Errorf("statistics should be %+v but %+v", expected, actual)
+	}
+	if GetBatchSizeStatistics(nil) != (BatchSizeStatistics{}) {
+		t.Errorf("statistics without releases should be zero but %+v", GetBatchSizeStatistics(nil))
+	}
+}
+
+func TestMatchesPathPatternsShouldMatchGlobAndParentDirectories(t *testing.T) {
+	testCases := []struct {
+		patterns []string
+		name     string
+		expected bool
+	}{
+		{[]string{"payments"}, "payments/api/handler.go", true},
+		{[]string{"payments/*.go"}, "payments/main.go", true},
+		{[]string{"payments/*.go"}, "payments/api/handler.go", false},
+		{[]string{"**/*.go"}, "payments/api/handler.go", true},
+		{[]string{"payments/**"}, "payments/api/handler.go", true},
+		{[]string{"payments/**"}, "web/index.html", false},
+		{[]string{"web", "payments/api"}, "payments/main.go", false},
+	}
+	for _, testCase := range testCases {
+		if actual := matchesPathPatterns(testCase.patterns, testCase.name); actual != testCase.expected {
+			t.Errorf("%v should match %v is %v but %v", testCase.patterns, testCase.name, testCase.expected, actual)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -10,7 +11,25 @@ type Release struct {
 	Date               time.Time     `json:"date"`
 	LeadTimeForChanges time.Duration `json:"leadTimeForChanges"`
 	Result             ReleaseResult `json:"result"`
-	isRestored         bool          `json:"-"`
+	// IsRework is true if the release is an unplanned fix detected by failure detectors regardless of failure source
+	IsRework bool `json:"isRework"`
+	// BatchSize is the size of changes from the previous release. Changed files and lines are counted only if Option.CountsChangedLines
+	BatchSize BatchSize `json:"batchSize"`
+	// Component is the component of the release in monorepo mode
+	Component string `json:"component,omitempty"`
+	// IsOutlier is true if lead time for changes or time to restore of the release is excluded or winsorized as outlier
+	IsOutlier bool      `json:"isOutlier"`
+	commits   []*Commit `json:"-"`
+	// hash is the hash of the released commit
+	hash string `json:"-"`
+	// restorations are restorations attributed to this release
+	restorations []Restoration `json:"-"`
+	// isInitial is true if the release has no previous release, so that its commits are from the first commit of the repository
+	isInitial bool `json:"-"`
+	// isLeadTimeExcluded is true if lead time for changes of the release is not used for statistics
+	isLeadTimeExcluded bool `json:"-"`
+	// outliers is how outliers of the release are treated. Values are used as they are if nil
+	outliers *outlierTreatment `json:"-"`
 }
 
 func (r *Release) String() string {
@@ -23,3 +42,78 @@ func (r *Release) Equal(another *Release) bool {
 		r.LeadTimeForChanges.Nanoseconds() == another.LeadTimeForChanges.Nanoseconds() &&
 		r.Result.Equal(another.Result)
 }
+
+// Commits returns commits shipped by the release, which are not included in the previous release.
+func (r *Release) Commits() []*Commit {
+	return r.commits
+}
+
+// LeadTimeForChangesOf returns duration from commit to the release date.
+func (r *Release) LeadTimeForChangesOf(commit *Commit) time.Duration {
+	return r.Date.Sub(commit.CommitterWhen)
+}
+
+// Restorations returns restorations attributed to the release.
+// A restoration is attributed to the restoring release, or to the failed release if it is restored by an incident resolution.
+func (r *Release) Restorations() []Restoration {
+	return r.restorations
+}
+
+// LeadTimesForChanges returns samples of lead time for changes of the release measured by mode.
+// Outliers are not treated.
+func (r *Release) LeadTimesForChanges(mode LeadTimeMode) []time.Duration {
+	if mode == LeadTimeModePerCommit {
+		return r.leadTimesForChangesPerCommit()
+	}
+	return []time.Duration{r.LeadTimeForChanges}
+}
+
+// WorkingLeadTimesForChanges returns samples of lead time for changes of the release measured by mode,
+// counting only working time of calendar. They are in the same order as LeadTimesForChanges.
+func (r *Release) WorkingLeadTimesForChanges(mode LeadTimeMode, calendar *WorkingCalendar) []time.Duration {
+	if mode == LeadTimeModePerCommit {
+		leadTimes := make([]time.Duration, 0, len(r.commits))
+		for _, commit := range r.commits {
+			leadTimes = append(leadTimes, calendar.WorkingDuration(commit.CommitterWhen, r.Date))
+		}
+		return leadTimes
+	}
+	return []time.Duration{calendar.WorkingDuration(r.Date.Add(-r.LeadTimeForChanges), r.Date)}
+}
+
+// leadTimesForChangesPerCommit returns durations from each commit of the release to the release date.
+func (r *Release) leadTimesForChangesPerCommit() []time.Duration {
+	leadTimes := make([]time.Duration, 0, len(r.commits))
+	for _, commit := range r.commits {
+		leadTimes = append(leadTimes, r.LeadTimeForChangesOf(commit))
+	}
+	return leadTimes
+}
+
+// isReleaseOf returns true if ref is the tag or the (abbreviated) commit hash of the release.
+func (r *Release) isReleaseOf(ref string) bool {
+	if ref == "" {
+		return false
+	}
+	return r.Tag == ref || (len(ref) >= 7 && strings.HasPrefix(r.hash, ref))
+}
+
+// restores returns true if the release has restored another.
+func (r *Release) restores(another *Release) bool {
+	for _, restoration := range r.restorations {
+		if restoration.FailedRelease == another {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCommit returns true if the release shipped the commit of (abbreviated) hash.
+func (r *Release) hasCommit(hash string) bool {
+	for _, commit := range r.commits {
+		if strings.HasPrefix(commit.Hash, hash) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// releaseCacheVersion is the version of the cache file format. Cache files of other versions are discarded
+const releaseCacheVersion = 1
+
+// ReleaseCache stores commits and diff stat of each release on disk, so that history is walked only for new or moved releases.
+// Entries are keyed by the commit of the release, the commit of the previous release and Option.Paths,
+// and failures and lead time are calculated from the cached commits in each query.
+// Entries that are not used since the cache is opened are evicted on Save, so that the file does not grow with moved tags or rewritten history.
+// It is safe for concurrent use.
+type ReleaseCache struct {
+	path    string
+	mutex   sync.Mutex
+	entries map[string]releaseCacheEntry
+	// usedKeys are keys got or put since the cache is opened
+	usedKeys map[string]bool
+	isDirty  bool
+}
+
+type releaseCacheFile struct {
+	Version int                          `json:"version"`
+	Entries map[string]releaseCacheEntry `json:"entries"`
+}
+
+type releaseCacheEntry struct {
+	Commits []*Commit `json:"commits"`
+	// HasChangedLines is true if FilesChanged, LinesAdded and LinesDeleted are counted by Option.CountsChangedLines
+	HasChangedLines bool `json:"hasChangedLines"`
+	FilesChanged    int  `json:"filesChanged"`
+	LinesAdded      int  `json:"linesAdded"`
+	LinesDeleted    int  `json:"linesDeleted"`
+}
+
+// DefaultReleaseCacheDirectory returns "four-keys" in the user cache directory, e.g. $XDG_CACHE_HOME/four-keys.
+func DefaultReleaseCacheDirectory() (string, error) {
+	directory, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(directory, "four-keys"), nil
+}
+
+// OpenReleaseCache opens the cache of repositoryIdentity (e.g. url or directory of the repository) in directory.
+// The cache is empty if the file does not exist or is not readable.
+func OpenReleaseCache(directory string, repositoryIdentity string) *ReleaseCache {
+	hash := sha256.Sum256([]byte(repositoryIdentity))
+	cache := &ReleaseCache{
+		path:     filepath.Join(directory, hex.EncodeToString(hash[:16])+".json"),
+		entries:  make(map[string]releaseCacheEntry),
+		usedKeys: make(map[string]bool),
+	}
+	data, err := os.ReadFile(cache.path)
+	if err != nil {
+		return cache
+	}
+	var file releaseCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != releaseCacheVersion || file.Entries == nil {
+		return cache
+	}
+	cache.entries = file.Entries
+	return cache
+}
+
+// Path returns the path of the cache file.
+func (c *ReleaseCache) Path() string {
+	return c.path
+}
+
+// Save evicts unused entries and writes the cache file if the entries are changed.
+func (c *ReleaseCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if !c.usedKeys[key] {
+			delete(c.entries, key)
+			c.isDirty = true
+		}
+	}
+	if !c.isDirty {
+		return nil
+	}
+	data, err := json.Marshal(releaseCacheFile{Version: releaseCacheVersion, Entries: c.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	// the file is replaced by rename so that other processes do not read a partially written file
+	temporaryFile, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporaryFile.Name())
+	if _, err := temporaryFile.Write(data); err != nil {
+		temporaryFile.Close()
+		return err
+	}
+	if err := temporaryFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temporaryFile.Name(), c.path); err != nil {
+		return err
+	}
+	c.isDirty = false
+	return nil
+}
+
+// get returns the entry of key. It always misses if the cache is nil.
+func (c *ReleaseCache) get(key string) (releaseCacheEntry, bool) {
+	if c == nil {
+		return releaseCacheEntry{}, false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[key]
+	if ok {
+		c.usedKeys[key] = true
+	}
+	return entry, ok
+}
+
+// put stores the entry of key. It does nothing if the cache is nil.
+func (c *ReleaseCache) put(key string, entry releaseCacheEntry) {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[key] = entry
+	c.usedKeys[key] = true
+	c.isDirty = true
+}
+
+// releaseCacheKey returns the key of sources[i] compared with the previous release for paths.
+func releaseCacheKey(sources []ReleaseSource, i int, paths []string) string {
+	previousHash := ""
+	if i < len(sources)-1 {
+		previousHash = sources[i+1].commit.Hash.String()
+	}
+	return previousHash + ".." + sources[i].commit.Hash.String() + ":" + strings.Join(paths, "\x00")
+}
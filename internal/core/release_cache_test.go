@@ -0,0 +1,159 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/hmiyado/four-keys/internal/util"
+)
+
+func TestQueryReleasesShouldUseReleaseCache(t *testing.T) {
+	repository, err := util.NewRepositoryBuilderOnDisk(t.TempDir()).GitGraph(util.ExampleGitGraph).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	directory := t.TempDir()
+	newOption := func(cache *ReleaseCache, paths []string) *Option {
+		return &Option{
+			Since:              time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+			Until:              time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+			IsLocalRepository:  true,
+			Paths:              paths,
+			CountsChangedLines: true,
+			ReleaseCache:       cache,
+		}
+	}
+	expectedReleases := QueryReleases(repository, newOption(nil, nil))
+
+	cache := OpenReleaseCache(directory, "example")
+	assertReleasesAreEqual(t, expectedReleases, QueryReleases(repository, newOption(cache, nil)))
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopenedCache := OpenReleaseCache(directory, "example")
+	if len(reopenedCache.entries) != len(expectedReleases) {
+		t.Fatalf("cache should have entries of %v releases but %v", len(expectedReleases), len(reopenedCache.entries))
+	}
+	// entries are replaced so that the result shows the cache is used instead of history
+	for key, entry := range reopenedCache.entries {
+		entry.LinesAdded = 999
+		reopenedCache.entries[key] = entry
+	}
+	releases := QueryReleases(repository, newOption(reopenedCache, nil))
+	assertReleasesAreEqual(t, expectedReleases, releases)
+	for _, release := range releases {
+		if release.BatchSize.LinesAdded != 999 {
+			t.Errorf("%v should be found in cache but %+v", release.Tag, release.BatchSize)
+		}
+		if len(release.Commits()) == 0 || release.Commits()[0].CommitterWhen.IsZero() {
+			t.Errorf("commits of %v should be restored from cache but %v", release.Tag, release.Commits())
+		}
+	}
+
+	// other paths are not cached
+	for _, release := range QueryReleases(repository, newOption(reopenedCache, []string{"**"})) {
+		if release.BatchSize.LinesAdded == 999 {
+			t.Errorf("%v should not be found in cache for other paths", release.Tag)
+		}
+	}
+
+	// entries without changed lines are used only if changed lines are not counted
+	for key, entry := range reopenedCache.entries {
+		entry.HasChangedLines = false
+		for _, commit := range entry.Commits {
+			commit.AuthorName = "cached"
+		}
+		reopenedCache.entries[key] = entry
+	}
+	withoutChangedLinesOption := newOption(reopenedCache, nil)
+	withoutChangedLinesOption.CountsChangedLines = false
+	for _, release := range QueryReleases(repository, withoutChangedLinesOption) {
+		if len(release.Commits()) == 0 || release.Commits()[0].AuthorName != "cached" || release.BatchSize.LinesAdded != 0 {
+			t.Errorf("commits of %v should be restored from cache without changed lines but %v %+v", release.Tag, release.Commits(), release.BatchSize)
+		}
+	}
+	for _, release := range QueryReleases(repository, newOption(reopenedCache, nil)) {
+		if release.BatchSize.LinesAdded == 999 {
+			t.Errorf("changed lines of %v should be counted again", release.Tag)
+		}
+	}
+}
+
+func TestQueryReleasesShouldNotCacheReleasesThatCannotBeRead(t *testing.T) {
+	repository, err := util.NewRepositoryFromGitGraph(util.ExampleGitGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// d1 is removed so that history of v2.0.0 and later releases cannot be walked
+	storage := repository.Storer.(*memory.Storage)
+	for hash, commit := range storage.Commits {
+		if decodedCommit, err := object.DecodeCommit(storage, commit); err == nil && strings.TrimSpace(decodedCommit.Message) == "d1" {
+			delete(storage.Commits, hash)
+			delete(storage.Objects, hash)
+		}
+	}
+	cache := OpenReleaseCache(t.TempDir(), "example")
+	QueryReleases(repository, &Option{
+		Since:        time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC),
+		Until:        time.Date(2023, 5, 31, 0, 0, 0, 0, time.UTC),
+		ReleaseCache: cache,
+	})
+	sources := queryReleaseSources(repository, nil)
+	for i, source := range sources {
+		_, ok := cache.get(releaseCacheKey(sources, i, nil))
+		isReadable := source.name == "v1.0.0" || source.name == "v0.1.0"
+		if ok != isReadable {
+			t.Errorf("%v should be cached (%v) but %v", source.name, isReadable, ok)
+		}
+	}
+}
+
+func TestReleaseCacheShouldEvictEntriesThatAreNotUsed(t *testing.T) {
+	directory := t.TempDir()
+	cache := OpenReleaseCache(directory, "example")
+	cache.put("used", releaseCacheEntry{})
+	cache.put("unused", releaseCacheEntry{})
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopenedCache := OpenReleaseCache(directory, "example")
+	if _, ok := reopenedCache.get("used"); !ok {
+		t.Fatalf("cache should have the entry used in the previous run")
+	}
+	if err := reopenedCache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	entries := OpenReleaseCache(directory, "example").entries
+	if _, ok := entries["used"]; !ok || len(entries) != 1 {
+		t.Errorf("cache should have only the used entry but %v", entries)
+	}
+}
+
+func TestOpenReleaseCacheShouldDiscardInvalidFile(t *testing.T) {
+	directory := t.TempDir()
+	cache := OpenReleaseCache(directory, "example")
+	cache.put("key", releaseCacheEntry{Commits: []*Commit{{Hash: "abc"}}})
+	if err := cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(cache.Path()) != directory {
+		t.Errorf("cache file should be in %v but %v", directory, cache.Path())
+	}
+	if _, ok := OpenReleaseCache(directory, "another").get("key"); ok {
+		t.Errorf("cache of another repository should not have the entry")
+	}
+
+	for _, content := range []string{"{", `{"version":0,"entries":{"key":{"commits":[]}}}`} {
+		os.WriteFile(cache.Path(), []byte(content), 0644)
+		if _, ok := OpenReleaseCache(directory, "example").get("key"); ok {
+			t.Errorf("cache should be empty for %v", content)
+		}
+	}
+}
@@ -6,7 +6,10 @@ import (
 )
 
 type ReleaseResult struct {
-	IsSuccess     bool
+	IsSuccess bool
+	// TimeToRestore is the time to restore of the failed release that this release restored.
+	// It is nil if this release restored no failure detected by failure detectors.
+	// Restorations by incident resolutions have no restoring release, so that they are not set.
 	TimeToRestore *time.Duration
 }
 
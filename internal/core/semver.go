@@ -0,0 +1,108 @@
+package core
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSemanticVersion = errors.New("invalid semantic version")
+
+// semanticVersionPattern matches semantic version 2.0.0 with optional "v" prefix, e.g. v1.2.3-rc.1+build.5
+var semanticVersionPattern = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// SemanticVersion is a version defined by https://semver.org
+type SemanticVersion struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	// Prerelease is dot-separated identifiers, e.g. ["rc", "1"] for 1.0.0-rc.1
+	Prerelease []string
+	Build      string
+}
+
+// parseSemanticVersion parses name as semantic version.
+// It returns ErrInvalidSemanticVersion if name is not semantic version.
+func parseSemanticVersion(name string) (*SemanticVersion, error) {
+	matches := semanticVersionPattern.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, ErrInvalidSemanticVersion
+	}
+	numbers := make([]uint64, 3)
+	for i := range numbers {
+		number, err := strconv.ParseUint(matches[i+1], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidSemanticVersion
+		}
+		numbers[i] = number
+	}
+	var prerelease []string
+	if matches[4] != "" {
+		prerelease = strings.Split(matches[4], ".")
+	}
+	return &SemanticVersion{
+		Major:      numbers[0],
+		Minor:      numbers[1],
+		Patch:      numbers[2],
+		Prerelease: prerelease,
+		Build:      matches[5],
+	}, nil
+}
+
+// isPrereleaseName returns true if name is semantic version with prerelease identifiers.
+func isPrereleaseName(name string) bool {
+	version, err := parseSemanticVersion(name)
+	return err == nil && version.IsPrerelease()
+}
+
+// IsPrerelease returns true if the version has prerelease identifiers.
+func (v *SemanticVersion) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0 or +1 by precedence of v and another.
+// Build metadata is ignored.
+func (v *SemanticVersion) Compare(another *SemanticVersion) int {
+	for _, pair := range [][2]uint64{{v.Major, another.Major}, {v.Minor, another.Minor}, {v.Patch, another.Patch}} {
+		if pair[0] != pair[1] {
+			return compareUint(pair[0], pair[1])
+		}
+	}
+	// a version without prerelease has higher precedence than with prerelease
+	if !v.IsPrerelease() || !another.IsPrerelease() {
+		return compareUint(uint64(len(another.Prerelease)), uint64(len(v.Prerelease)))
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(another.Prerelease); i++ {
+		if result := comparePrereleaseIdentifier(v.Prerelease[i], another.Prerelease[i]); result != 0 {
+			return result
+		}
+	}
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(another.Prerelease)))
+}
+
+// comparePrereleaseIdentifier compares numeric identifiers numerically and others lexically.
+// Numeric identifiers have lower precedence than others.
+func comparePrereleaseIdentifier(a string, b string) int {
+	aNumber, aErr := strconv.ParseUint(a, 10, 64)
+	bNumber, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(aNumber, bNumber)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareUint(a uint64, b uint64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestParseSemanticVersionShouldParseVersion(t *testing.T) {
+	version, err := parseSemanticVersion("v1.2.3-rc.1+build.5")
+	if err != nil {
+		t.Fatalf("v1.2.3-rc.1+build.5 should be semantic version but %v", err)
+	}
+	if version.Major != 1 || version.Minor != 2 || version.Patch != 3 || len(version.Prerelease) != 2 || version.Prerelease[0] != "rc" || version.Build != "build.5" {
+		t.Errorf("v1.2.3-rc.1+build.5 is parsed as %+v", version)
+	}
+	for _, name := range []string{"docs-2023", "v1.2", "1.02.3", "v1.2.3-"} {
+		if _, err := parseSemanticVersion(name); err != ErrInvalidSemanticVersion {
+			t.Errorf("%v should not be semantic version", name)
+		}
+	}
+}
+
+func TestSemanticVersionCompareShouldFollowPrecedence(t *testing.T) {
+	// https://semver.org/#spec-item-11
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		lower, _ := parseSemanticVersion(ordered[i])
+		higher, _ := parseSemanticVersion(ordered[i+1])
+		if lower.Compare(higher) != -1 || higher.Compare(lower) != 1 {
+			t.Errorf("%v should be lower than %v", ordered[i], ordered[i+1])
+		}
+	}
+	a, _ := parseSemanticVersion("v1.0.0+a")
+	b, _ := parseSemanticVersion("1.0.0+b")
+	if a.Compare(b) != 0 {
+		t.Errorf("build metadata should be ignored")
+	}
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// getMeanDuration returns mean of durations. It returns 0 if durations is empty.
+func getMeanDuration(durations []time.Duration) time.Duration {
+	if len(durations) == 0 {
+		return time.Duration(0)
+	}
+	sum := time.Duration(0)
+	for _, duration := range durations {
+		sum += duration
+	}
+	return time.Duration(int64(sum) / int64(len(durations)))
+}
+
+// getPercentileDuration returns p-th percentile (0 <= p <= 100) of durations.
+// Percentile is linearly interpolated between the closest ranks. It returns 0 if durations is empty.
+func getPercentileDuration(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return time.Duration(0)
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank - float64(lower)
+	return sorted[lower] + time.Duration(math.Round(fraction*float64(sorted[lower+1]-sorted[lower])))
+}
+
+// getMean returns mean of values. It returns 0 if values is empty.
+func getMean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := float64(0)
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+// getPercentile returns p-th percentile (0 <= p <= 100) of values in the same way as getPercentileDuration.
+func getPercentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	fraction := rank - float64(lower)
+	return sorted[lower] + fraction*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const dateLayout = "2006-01-02"
+
+// WorkingCalendar counts only working time, so that weekends, holidays and nights are not counted in durations.
+type WorkingCalendar struct {
+	// WorkingDays are weekdays to work
+	WorkingDays []time.Weekday
+	// WorkingHoursStart and WorkingHoursEnd are offsets from midnight, e.g. 9h and 18h.
+	// Whole day is working hours if both are zero
+	WorkingHoursStart time.Duration
+	WorkingHoursEnd   time.Duration
+	// Holidays are dates (2006-01-02) that are not working days
+	Holidays map[string]bool
+	// Location is the timezone of working days and hours. UTC is used if nil
+	Location *time.Location
+}
+
+// NewWorkingCalendar returns WorkingCalendar of Monday to Friday without holidays, whose working hours are whole day.
+func NewWorkingCalendar() *WorkingCalendar {
+	return &WorkingCalendar{
+		WorkingDays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Holidays:    make(map[string]bool),
+	}
+}
+
+// ReadHolidays reads a list of holidays (2006-01-02) from path.
+// The file is YAML if its extension is ".yaml" or ".yml", otherwise JSON.
+func ReadHolidays(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dates := make([]string, 0)
+	extension := strings.ToLower(filepath.Ext(path))
+	if extension == ".yaml" || extension == ".yml" {
+		err = yaml.Unmarshal(data, &dates)
+	} else {
+		err = json.Unmarshal(data, &dates)
+	}
+	if err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]bool)
+	for i, date := range dates {
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			return nil, fmt.Errorf("holidays[%v]: %v", i, err)
+		}
+		holidays[date] = true
+	}
+	return holidays, nil
+}
+
+// WorkingDuration returns working time from start to end.
+// It is negative if end is before start.
+// Whole weeks without holidays and changes of the UTC offset are counted at once, and the other days are counted day by day.
+func (c *WorkingCalendar) WorkingDuration(start time.Time, end time.Time) time.Duration {
+	if end.Before(start) {
+		return -c.WorkingDuration(end, start)
+	}
+	location := c.Location
+	if location == nil {
+		location = time.UTC
+	}
+	start, end = start.In(location), end.In(location)
+	holidays := c.sortedHolidays()
+	duration := time.Duration(0)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, location)
+	for day.Before(end) {
+		nextWeek := day.AddDate(0, 0, 7)
+		if !day.Before(start) && !nextWeek.After(end) && isRegularWeek(day, nextWeek, holidays) {
+			duration += c.workingDurationOfRegularWeek()
+			day = nextWeek
+			continue
+		}
+		if c.isWorkingDay(day) {
+			duration += c.workingDurationOfDay(day, start, end)
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return duration
+}
+
+// workingDurationOfDay returns working time of day from start to end.
+// Working hours are wall clock times of the day, so that they are not shifted by daylight saving time.
+func (c *WorkingCalendar) workingDurationOfDay(day time.Time, start time.Time, end time.Time) time.Duration {
+	workStart, workEnd := day, day.AddDate(0, 0, 1)
+	if c.WorkingHoursStart != 0 || c.WorkingHoursEnd != 0 {
+		workStart, workEnd = clockOfDay(day, c.WorkingHoursStart), clockOfDay(day, c.WorkingHoursEnd)
+	}
+	if workStart.Before(start) {
+		workStart = start
+	}
+	if workEnd.After(end) {
+		workEnd = end
+	}
+	if workEnd.After(workStart) {
+		return workEnd.Sub(workStart)
+	}
+	return 0
+}
+
+// workingDurationOfRegularWeek returns working time of a week without holidays and changes of the UTC offset.
+func (c *WorkingCalendar) workingDurationOfRegularWeek() time.Duration {
+	workingHours := 24 * time.Hour
+	if c.WorkingHoursStart != 0 || c.WorkingHoursEnd != 0 {
+		workingHours = max(c.WorkingHoursEnd-c.WorkingHoursStart, 0)
+	}
+	duration := time.Duration(0)
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if c.isWorkingWeekday(weekday) {
+			duration += workingHours
+		}
+	}
+	return duration
+}
+
+// isRegularWeek returns true if the week from day to nextWeek has neither holidays nor changes of the UTC offset.
+func isRegularWeek(day time.Time, nextWeek time.Time, holidays []string) bool {
+	_, offset := day.Zone()
+	_, nextOffset := nextWeek.Zone()
+	if offset != nextOffset {
+		return false
+	}
+	// dates of dateLayout are sorted in the order of strings
+	i, _ := slices.BinarySearch(holidays, day.Format(dateLayout))
+	return i == len(holidays) || holidays[i] >= nextWeek.Format(dateLayout)
+}
+
+// sortedHolidays returns Holidays in ascending order.
+func (c *WorkingCalendar) sortedHolidays() []string {
+	holidays := make([]string, 0, len(c.Holidays))
+	for date, isHoliday := range c.Holidays {
+		if isHoliday {
+			holidays = append(holidays, date)
+		}
+	}
+	slices.Sort(holidays)
+	return holidays
+}
+
+// clockOfDay returns the wall clock time of offset (e.g. 9h) in day.
+func clockOfDay(day time.Time, offset time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(offset/time.Hour), int(offset%time.Hour/time.Minute), int(offset%time.Minute/time.Second), 0, day.Location())
+}
+
+func (c *WorkingCalendar) isWorkingDay(day time.Time) bool {
+	if c.Holidays[day.Format(dateLayout)] {
+		return false
+	}
+	return c.isWorkingWeekday(day.Weekday())
+}
+
+func (c *WorkingCalendar) isWorkingWeekday(weekday time.Weekday) bool {
+	return slices.Contains(c.WorkingDays, weekday)
+}
@@ -0,0 +1,131 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWorkingCalendarShouldCountOnlyWorkingTime(t *testing.T) {
+	// 2023-04-14 is Friday and 2023-04-17 is Monday
+	fridayEvening := time.Date(2023, 4, 14, 18, 0, 0, 0, time.UTC)
+	mondayMorning := time.Date(2023, 4, 17, 10, 0, 0, 0, time.UTC)
+	businessHours := NewWorkingCalendar()
+	businessHours.WorkingHoursStart = 9 * time.Hour
+	businessHours.WorkingHoursEnd = 18 * time.Hour
+	withHoliday := NewWorkingCalendar()
+	withHoliday.Holidays["2023-04-17"] = true
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	inTokyo := NewWorkingCalendar()
+	inTokyo.Location = tokyo
+
+	testCases := []struct {
+		name     string
+		calendar *WorkingCalendar
+		start    time.Time
+		end      time.Time
+		expected time.Duration
+	}{
+		{"weekend is not counted", NewWorkingCalendar(), fridayEvening, mondayMorning, 16 * time.Hour},
+		{"nights are not counted", businessHours, fridayEvening, mondayMorning, time.Hour},
+		{"holiday is not counted", withHoliday, fridayEvening, mondayMorning, 6 * time.Hour},
+		{"working days are in location", inTokyo, fridayEvening, mondayMorning, 19 * time.Hour},
+		{"negative if end is before start", businessHours, mondayMorning, fridayEvening, -time.Hour},
+		{"in the same day", businessHours, mondayMorning, mondayMorning.Add(2 * time.Hour), 2 * time.Hour},
+	}
+	for _, testCase := range testCases {
+		actual := testCase.calendar.WorkingDuration(testCase.start, testCase.end)
+		if actual != testCase.expected {
+			t.Errorf("%v: working duration should be %v but %v", testCase.name, testCase.expected, actual)
+		}
+	}
+}
+
+func TestWorkingCalendarShouldCountWorkingHoursInDaylightSavingTime(t *testing.T) {
+	newYork, _ := time.LoadLocation("America/New_York")
+	everyDay := &WorkingCalendar{
+		WorkingDays: []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+		Location:    newYork,
+	}
+	everyDayBusinessHours := *everyDay
+	everyDayBusinessHours.WorkingHoursStart = 9 * time.Hour
+	everyDayBusinessHours.WorkingHoursEnd = 18 * time.Hour
+	businessDays := NewWorkingCalendar()
+	businessDays.Location = newYork
+	businessDays.WorkingHoursStart = 9 * time.Hour
+	businessDays.WorkingHoursEnd = 18 * time.Hour
+	businessDays.Holidays["2023-07-04"] = true
+	businessDays.Holidays["2023-12-25"] = true
+
+	testCases := []struct {
+		name     string
+		calendar *WorkingCalendar
+		start    time.Time
+		end      time.Time
+		expected time.Duration
+	}{
+		// daylight saving time starts at 2023-03-12 02:00
+		{"working hours are wall clock times", &everyDayBusinessHours, time.Date(2023, 3, 12, 9, 0, 0, 0, newYork), time.Date(2023, 3, 12, 12, 0, 0, 0, newYork), 3 * time.Hour},
+		{"day of daylight saving time is 23 hours", everyDay, time.Date(2023, 3, 1, 0, 0, 0, 0, newYork), time.Date(2023, 4, 1, 0, 0, 0, 0, newYork), 31*24*time.Hour - time.Hour},
+		// 2023 has 260 weekdays, and 2 of them are holidays
+		{"weeks of a year", businessDays, time.Date(2023, 1, 1, 0, 0, 0, 0, newYork), time.Date(2024, 1, 1, 0, 0, 0, 0, newYork), 258 * 9 * time.Hour},
+	}
+	for _, testCase := range testCases {
+		actual := testCase.calendar.WorkingDuration(testCase.start, testCase.end)
+		if actual != testCase.expected {
+			t.Errorf("%v: working duration should be %v but %v", testCase.name, testCase.expected, actual)
+		}
+	}
+}
+
+func TestReadHolidaysShouldReadDates(t *testing.T) {
+	directory := t.TempDir()
+	yamlPath := filepath.Join(directory, "holidays.yaml")
+	os.WriteFile(yamlPath, []byte("- 2023-05-03\n- 2023-05-04\n"), 0644)
+	invalidPath := filepath.Join(directory, "holidays.json")
+	os.WriteFile(invalidPath, []byte(`["2023/05/03"]`), 0644)
+
+	holidays, err := ReadHolidays(yamlPath)
+	if err != nil || len(holidays) != 2 || !holidays["2023-05-03"] || !holidays["2023-05-04"] {
+		t.Errorf("holidays should be 2023-05-03 and 2023-05-04 but %v (%v)", holidays, err)
+	}
+	if _, err := ReadHolidays(invalidPath); err == nil {
+		t.Errorf("holidays not in 2006-01-02 should be error")
+	}
+}
+
+func TestGetWorkingStatisticsShouldUseWorkingTime(t *testing.T) {
+	// v1 fails on Friday evening and v2 restores it on Monday morning
+	v1 := &Release{
+		Tag:                "v1",
+		Date:               time.Date(2023, 4, 14, 18, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: 24 * time.Hour,
+		commits:            []*Commit{{CommitterWhen: time.Date(2023, 4, 13, 18, 0, 0, 0, time.UTC)}},
+	}
+	v2 := &Release{
+		Tag:                "v2",
+		Date:               time.Date(2023, 4, 17, 10, 0, 0, 0, time.UTC),
+		LeadTimeForChanges: 64 * time.Hour,
+		commits:            []*Commit{{CommitterWhen: time.Date(2023, 4, 14, 18, 0, 0, 0, time.UTC)}},
+	}
+	v2.restorations = []Restoration{{FailedRelease: v1, RestoringRelease: v2, TimeToRestore: 64 * time.Hour, Start: v1.Date, End: v2.Date}}
+	releases := []*Release{v2, v1}
+	calendar := NewWorkingCalendar()
+	calendar.WorkingHoursStart = 9 * time.Hour
+	calendar.WorkingHoursEnd = 18 * time.Hour
+
+	for _, mode := range []LeadTimeMode{LeadTimeModeFirstCommit, LeadTimeModePerCommit} {
+		leadTime := GetWorkingLeadTimeForChangesStatistics(releases, mode, calendar)
+		if leadTime.Mean != 5*time.Hour || leadTime.Median != 5*time.Hour {
+			t.Errorf("working lead time in %v should be mean of 9h and 1h but %+v", mode, leadTime)
+		}
+	}
+	timeToRestore := GetWorkingTimeToRestoreStatistics(releases, calendar)
+	if timeToRestore.Mean != time.Hour || timeToRestore.Restorations[0].TimeToRestore != time.Hour {
+		t.Errorf("working time to restore should be 1h but %+v", timeToRestore)
+	}
+	if GetTimeToRestoreStatistics(releases).Mean != 64*time.Hour {
+		t.Errorf("wall-clock time to restore should not be changed but %v", GetTimeToRestoreStatistics(releases).Mean)
+	}
+}
@@ -0,0 +1,154 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+var gitGraphAttributePattern = regexp.MustCompile(`(\w+):\s*(?:"([^"]*)"|(\S+))`)
+
+// NewRepositoryFromGitGraph returns in-memory repository built by script.
+// See RepositoryBuilder.GitGraph for the syntax of script.
+func NewRepositoryFromGitGraph(script string) (*git.Repository, error) {
+	return NewRepositoryBuilder().GitGraph(script).Build()
+}
+
+// GitGraph applies script written in a subset of mermaid gitGraph to the builder.
+//
+//	gitGraph
+//	  commit id: "m1" tag: "v1" date: "2023-04-01"
+//	  branch develop
+//	  commit id: "d1" date: "2023-04-05" file: "src/main.go"
+//	  checkout main
+//	  merge develop id: "m2" annotatedTag: "v2" date: "2023-04-15"
+//
+// Commands are commit, branch, checkout (or switch) and merge. In addition to id, tag and type of mermaid,
+// commit and merge accept attributes below.
+//
+//   - msg: commit message (default is id)
+//   - date: committer date in "2006-01-02" or RFC3339
+//   - author: author name
+//   - authorDate: author date in "2006-01-02" or RFC3339
+//   - annotatedTag: annotated tag name
+//   - file: file to append a line (repeatable)
+func (b *RepositoryBuilder) GitGraph(script string) *RepositoryBuilder {
+	for i, line := range strings.Split(script, "\n") {
+		if b.err != nil {
+			return b
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "%%") || strings.HasPrefix(line, "gitGraph") {
+			continue
+		}
+		command, arguments, _ := strings.Cut(line, " ")
+		switch command {
+		case "commit":
+			options, tags, err := parseGitGraphAttributes(arguments)
+			if err != nil {
+				b.err = fmt.Errorf("line %v: %v", i+1, err)
+				return b
+			}
+			b.CommitWith(options)
+			b.applyGitGraphTags(tags)
+		case "branch", "checkout", "switch":
+			fields := strings.Fields(arguments)
+			if len(fields) == 0 {
+				b.err = fmt.Errorf("line %v: %v requires branch name", i+1, command)
+				return b
+			}
+			if command == "branch" {
+				b.Branch(fields[0])
+			} else {
+				b.Checkout(fields[0])
+			}
+		case "merge":
+			branch, attributes, _ := strings.Cut(strings.TrimSpace(arguments), " ")
+			if branch == "" {
+				b.err = fmt.Errorf("line %v: %v requires branch name", i+1, command)
+				return b
+			}
+			options, tags, err := parseGitGraphAttributes(attributes)
+			if err != nil {
+				b.err = fmt.Errorf("line %v: %v", i+1, err)
+				return b
+			}
+			if options.Message == "" {
+				options.Message = fmt.Sprintf("Merge branch '%v'", branch)
+			}
+			b.Merge(branch, options)
+			b.applyGitGraphTags(tags)
+		default:
+			b.err = fmt.Errorf("line %v: unsupported command %v", i+1, command)
+		}
+	}
+	return b
+}
+
+type gitGraphTags struct {
+	lightweight []string
+	annotated   []string
+}
+
+func (b *RepositoryBuilder) applyGitGraphTags(tags gitGraphTags) {
+	for _, tag := range tags.lightweight {
+		b.Tag(tag)
+	}
+	for _, tag := range tags.annotated {
+		b.AnnotatedTag(tag, b.lastWhen)
+	}
+}
+
+func parseGitGraphAttributes(attributes string) (CommitOptions, gitGraphTags, error) {
+	options := CommitOptions{}
+	tags := gitGraphTags{}
+	id := ""
+	for _, match := range gitGraphAttributePattern.FindAllStringSubmatch(attributes, -1) {
+		key := match[1]
+		value := match[2] + match[3]
+		switch key {
+		case "id":
+			id = value
+		case "msg":
+			options.Message = value
+		case "date":
+			when, err := parseGitGraphDate(value)
+			if err != nil {
+				return options, tags, err
+			}
+			options.CommitterWhen = when
+		case "author":
+			options.Author = value
+		case "authorDate":
+			when, err := parseGitGraphDate(value)
+			if err != nil {
+				return options, tags, err
+			}
+			options.AuthorWhen = when
+		case "tag":
+			tags.lightweight = append(tags.lightweight, value)
+		case "annotatedTag":
+			tags.annotated = append(tags.annotated, value)
+		case "file":
+			options.Files = append(options.Files, value)
+		case "type":
+			// type only affects how mermaid renders the commit
+		default:
+			return options, tags, fmt.Errorf("unsupported attribute %v", key)
+		}
+	}
+	if options.Message == "" {
+		options.Message = id
+	}
+	return options, tags, nil
+}
+
+func parseGitGraphDate(value string) (time.Time, error) {
+	if when, err := time.Parse("2006-01-02", value); err == nil {
+		return when, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
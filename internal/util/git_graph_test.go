@@ -0,0 +1,100 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewRepositoryFromGitGraphShouldCreateCommitsAndTags(t *testing.T) {
+	repository, err := NewRepositoryFromGitGraph(ExampleGitGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := repository.Tag("v2.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repository.CommitObject(tag.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "m3" || commit.NumParents() != 2 || commit.Committer.When.Format("2006-01-02") != "2023-04-30" {
+		t.Errorf("v2.0.1 should be merge commit m3 at 2023-04-30 but %v", commit)
+	}
+	hotfix, err := commit.Parent(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hotfix.Message != "h2" {
+		t.Errorf("second parent of m3 should be h2 but %v", hotfix.Message)
+	}
+}
+
+func TestNewRepositoryFromGitGraphShouldCreateAnnotatedTag(t *testing.T) {
+	repository, err := NewRepositoryFromGitGraph(ExampleGitGraph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := repository.Tag("v2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagObject, err := repository.TagObject(tag.Hash())
+	if err != nil {
+		t.Fatalf("v2.0.0 should be annotated tag but %v", err)
+	}
+	commit, err := tagObject.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "m2" {
+		t.Errorf("v2.0.0 should point m2 but %v", commit.Message)
+	}
+}
+
+func TestNewRepositoryFromGitGraphShouldStoreFilesInTree(t *testing.T) {
+	repository, err := NewRepositoryFromGitGraph(`gitGraph
+  commit id: "c1" file: "README.md" file: "services/api/main.go"
+  branch feature
+  commit id: "c2" file: "services/api/main.go"
+  checkout main
+  merge feature id: "c3" tag: "v1"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag, _ := repository.Tag("v1")
+	commit, _ := repository.CommitObject(tag.Hash())
+	file, err := commit.File("services/api/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := file.Contents()
+	if content != "c1\nc2\n" {
+		t.Errorf("services/api/main.go should have lines of c1 and c2 but %q", content)
+	}
+	if _, err := commit.File("README.md"); err != nil {
+		t.Errorf("README.md should exist but %v", err)
+	}
+}
+
+func TestNewRepositoryFromGitGraphShouldFailWithUnsupportedCommand(t *testing.T) {
+	_, err := NewRepositoryFromGitGraph(`gitGraph
+  cherry-pick id: "c1"
+`)
+	if err == nil {
+		t.Errorf("unsupported command should be error")
+	}
+}
+
+func TestNewRepositoryFromGitGraphShouldFailWithoutBranchName(t *testing.T) {
+	for _, command := range []string{"branch", "checkout", "switch", "merge"} {
+		_, err := NewRepositoryFromGitGraph("gitGraph\n  commit id: \"c1\"\n  " + command + "\n")
+		if err == nil || !strings.Contains(err.Error(), "line 3") {
+			t.Errorf("%v without branch name should be error of line 3 but %v", command, err)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package util
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// DefaultBranch is the branch that RepositoryBuilder starts with.
+const DefaultBranch = "main"
+
+// DefaultSignature is used for author and committer unless CommitOptions specifies them.
+var DefaultSignature = object.Signature{Name: "four-keys", Email: "four-keys@example.com"}
+
+// RepositoryBuilder builds a git repository with scripted commits, branches, merges and tags.
+// It is intended to create deterministic fixtures for tests without network.
+//
+// Methods record the first error and skip the rest, so that the error can be checked once by Build.
+type RepositoryBuilder struct {
+	repository *git.Repository
+	err        error
+	// currentBranch is the checked out branch
+	currentBranch string
+	// heads is the latest commit of each branch
+	heads map[string]plumbing.Hash
+	// files is the content of each file at the latest commit of each branch
+	files map[string]map[string]string
+	// lastWhen is used for commits whose time is not specified
+	lastWhen time.Time
+}
+
+// CommitOptions describes a commit created by RepositoryBuilder.
+type CommitOptions struct {
+	Message string
+	// Author defaults to DefaultSignature
+	Author string
+	// AuthorWhen defaults to CommitterWhen
+	AuthorWhen time.Time
+	// CommitterWhen defaults to one hour after the previous commit
+	CommitterWhen time.Time
+	// Files are appended a line for each
+	Files []string
+	// Contents replace the content of each file after Files are appended
+	Contents map[string]string
+}
+
+// NewRepositoryBuilder returns RepositoryBuilder for in-memory repository.
+func NewRepositoryBuilder() *RepositoryBuilder {
+	repository, err := git.Init(memory.NewStorage(), nil)
+	return newRepositoryBuilder(repository, err)
+}
+
+// NewRepositoryBuilderOnDisk returns RepositoryBuilder for repository at directory.
+// It is useful to run local git command against the repository.
+func NewRepositoryBuilderOnDisk(directory string) *RepositoryBuilder {
+	repository, err := git.PlainInit(directory, false)
+	return newRepositoryBuilder(repository, err)
+}
+
+func newRepositoryBuilder(repository *git.Repository, err error) *RepositoryBuilder {
+	b := &RepositoryBuilder{
+		repository:    repository,
+		err:           err,
+		currentBranch: DefaultBranch,
+		heads:         make(map[string]plumbing.Hash),
+		files:         map[string]map[string]string{DefaultBranch: {}},
+		lastWhen:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err == nil {
+		b.err = repository.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName(DefaultBranch)))
+	}
+	return b
+}
+
+// Build returns the repository or the first error occurred while building.
+func (b *RepositoryBuilder) Build() (*git.Repository, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.repository, nil
+}
+
+// Head returns the latest commit hash of the current branch.
+func (b *RepositoryBuilder) Head() plumbing.Hash {
+	return b.heads[b.currentBranch]
+}
+
+// Commit creates a commit with message on the current branch.
+func (b *RepositoryBuilder) Commit(message string, when time.Time) *RepositoryBuilder {
+	return b.CommitWith(CommitOptions{Message: message, CommitterWhen: when})
+}
+
+// CommitWith creates a commit described by options on the current branch.
+func (b *RepositoryBuilder) CommitWith(options CommitOptions) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	parents := make([]plumbing.Hash, 0)
+	if head, ok := b.heads[b.currentBranch]; ok {
+		parents = append(parents, head)
+	}
+	files := b.files[b.currentBranch]
+	for _, file := range options.Files {
+		files[file] += options.Message + "\n"
+	}
+	for file, content := range options.Contents {
+		files[file] = content
+	}
+	return b.commit(options, parents, files)
+}
+
+// Branch creates a branch from the current branch and checks it out.
+func (b *RepositoryBuilder) Branch(name string) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, ok := b.files[name]; ok {
+		b.err = fmt.Errorf("branch %v already exists", name)
+		return b
+	}
+	if head, ok := b.heads[b.currentBranch]; ok {
+		b.heads[name] = head
+		b.err = b.repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(name), head))
+	}
+	b.files[name] = copyFiles(b.files[b.currentBranch])
+	b.currentBranch = name
+	return b
+}
+
+// Checkout switches the current branch.
+func (b *RepositoryBuilder) Checkout(name string) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, ok := b.files[name]; !ok {
+		b.err = fmt.Errorf("branch %v does not exist", name)
+		return b
+	}
+	b.currentBranch = name
+	return b
+}
+
+// Merge creates a merge commit of branch into the current branch.
+// Files of the merged branch take precedence over files of the current branch.
+func (b *RepositoryBuilder) Merge(branch string, options CommitOptions) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	head, ok := b.heads[b.currentBranch]
+	mergedHead, mergedOk := b.heads[branch]
+	if !ok || !mergedOk {
+		b.err = fmt.Errorf("cannot merge %v into %v without commits", branch, b.currentBranch)
+		return b
+	}
+	files := b.files[b.currentBranch]
+	for name, content := range b.files[branch] {
+		files[name] = content
+	}
+	return b.commit(options, []plumbing.Hash{head, mergedHead}, files)
+}
+
+// Tag creates a lightweight tag at the latest commit of the current branch.
+func (b *RepositoryBuilder) Tag(name string) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.err = b.repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), b.Head()))
+	return b
+}
+
+// AnnotatedTag creates an annotated tag at the latest commit of the current branch.
+func (b *RepositoryBuilder) AnnotatedTag(name string, when time.Time) *RepositoryBuilder {
+	if b.err != nil {
+		return b
+	}
+	tagger := DefaultSignature
+	tagger.When = when
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     tagger,
+		Message:    name + "\n",
+		TargetType: plumbing.CommitObject,
+		Target:     b.Head(),
+	}
+	tagObject := b.repository.Storer.NewEncodedObject()
+	if err := tag.Encode(tagObject); err != nil {
+		b.err = err
+		return b
+	}
+	tagHash, err := b.repository.Storer.SetEncodedObject(tagObject)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.err = b.repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), tagHash))
+	return b
+}
+
+func (b *RepositoryBuilder) commit(options CommitOptions, parents []plumbing.Hash, files map[string]string) *RepositoryBuilder {
+	treeHash, err := b.storeTree(files, "")
+	if err != nil {
+		b.err = err
+		return b
+	}
+	committerWhen := options.CommitterWhen
+	if committerWhen.IsZero() {
+		committerWhen = b.lastWhen.Add(time.Hour)
+	}
+	b.lastWhen = committerWhen
+	authorWhen := options.AuthorWhen
+	if authorWhen.IsZero() {
+		authorWhen = committerWhen
+	}
+	author := DefaultSignature
+	if options.Author != "" {
+		author = object.Signature{Name: options.Author, Email: options.Author + "@example.com"}
+	}
+	author.When = authorWhen
+	committer := DefaultSignature
+	committer.When = committerWhen
+
+	commit := &object.Commit{
+		Author:       author,
+		Committer:    committer,
+		Message:      options.Message,
+		TreeHash:     treeHash,
+		ParentHashes: parents,
+	}
+	commitObject := b.repository.Storer.NewEncodedObject()
+	if err := commit.Encode(commitObject); err != nil {
+		b.err = err
+		return b
+	}
+	commitHash, err := b.repository.Storer.SetEncodedObject(commitObject)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.heads[b.currentBranch] = commitHash
+	b.err = b.repository.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(b.currentBranch), commitHash))
+	return b
+}
+
+// storeTree stores the tree of files under directory and its subtrees, and returns hash of the tree.
+func (b *RepositoryBuilder) storeTree(files map[string]string, directory string) (plumbing.Hash, error) {
+	entries := make([]object.TreeEntry, 0)
+	subdirectories := make(map[string]bool)
+	for name, content := range files {
+		relative := name
+		if directory != "" {
+			if !strings.HasPrefix(name, directory+"/") {
+				continue
+			}
+			relative = strings.TrimPrefix(name, directory+"/")
+		}
+		if subdirectory, _, isNested := strings.Cut(relative, "/"); isNested {
+			subdirectories[subdirectory] = true
+			continue
+		}
+		blobHash, err := b.storeBlob(content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: relative, Mode: filemode.Regular, Hash: blobHash})
+	}
+	for subdirectory := range subdirectories {
+		treeHash, err := b.storeTree(files, path.Join(directory, subdirectory))
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: subdirectory, Mode: filemode.Dir, Hash: treeHash})
+	}
+	// git sorts tree entries as if directory names had a trailing slash
+	sort.Slice(entries, func(i, j int) bool {
+		return treeEntrySortKey(entries[i]) < treeEntrySortKey(entries[j])
+	})
+
+	tree := &object.Tree{Entries: entries}
+	treeObject := b.repository.Storer.NewEncodedObject()
+	if err := tree.Encode(treeObject); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return b.repository.Storer.SetEncodedObject(treeObject)
+}
+
+func (b *RepositoryBuilder) storeBlob(content string) (plumbing.Hash, error) {
+	blobObject := b.repository.Storer.NewEncodedObject()
+	blobObject.SetType(plumbing.BlobObject)
+	writer, err := blobObject.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := writer.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return b.repository.Storer.SetEncodedObject(blobObject)
+}
+
+func treeEntrySortKey(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
+
+func copyFiles(files map[string]string) map[string]string {
+	copied := make(map[string]string, len(files))
+	for name, content := range files {
+		copied[name] = content
+	}
+	return copied
+}
@@ -15,3 +15,24 @@ func AssertIsNearBy(t *testing.T, actual float64, expected float64, epsilon floa
 	}
 	t.Errorf("actual should be in [%v, %v] but %v", expected*(1-epsilon), expected*(1+epsilon), actual)
 }
+
+// ExampleGitGraph is the example of README with a release before and after it.
+//
+//   - v2.0.0 is failure. LeadTimeForChanges is 10 days (from d1)
+//   - v2.0.1 hotfixes v2.0.0. LeadTimeForChanges is 5 days (from h1) and TimeToRestore is 15 days
+const ExampleGitGraph = `gitGraph
+  commit id: "initial" date: "2023-03-01"
+  commit id: "m0" date: "2023-03-10" tag: "v0.1.0"
+  commit id: "m1" date: "2023-04-01" tag: "v1.0.0"
+  branch develop
+  commit id: "d1" date: "2023-04-05"
+  commit id: "d2" date: "2023-04-10"
+  checkout main
+  merge develop id: "m2" date: "2023-04-15" annotatedTag: "v2.0.0"
+  branch hotfix
+  commit id: "h1" msg: "hotfix: h1" date: "2023-04-25"
+  commit id: "h2" date: "2023-04-27"
+  checkout main
+  merge hotfix id: "m3" date: "2023-04-30" tag: "v2.0.1"
+  commit id: "m4" date: "2023-05-20" tag: "v2.1.0"
+`